@@ -0,0 +1,329 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestOffersSource(t *testing.T) {
+	d, closer := NewTestDB(t)
+	defer closer()
+	ctx := context.Background()
+
+	t.Run("existing offers default to linkedin", func(t *testing.T) {
+		o, err := d.GetOffer(ctx, &GetOfferParams{Source: "linkedin", ID: "existing_offer"})
+		if err != nil {
+			t.Fatalf("unable to get seeded offer: %v", err)
+		}
+		if o.Source != "linkedin" {
+			t.Errorf("expected source 'linkedin', got '%s'", o.Source)
+		}
+	})
+
+	t.Run("same id from different sources doesn't clash", func(t *testing.T) {
+		q, err := d.GetQuery(ctx, &GetQueryParams{Keywords: "golang", Location: "berlin"})
+		if err != nil {
+			t.Fatalf("unable to get seeded query: %v", err)
+		}
+		before, err := d.ListOffers(ctx, &ListOffersParams{ID: q.ID})
+		if err != nil {
+			t.Fatalf("unable to list offers: %v", err)
+		}
+		for _, src := range []string{"linkedin", "indeed", "indeed"} {
+			o := &CreateOfferParams{
+				ID:       "4242",
+				Title:    "Golang Developer",
+				Company:  src + " GmbH",
+				Location: "Berlin",
+				PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+				Source:   src,
+			}
+			if _, err := d.CreateOffer(ctx, o); err != nil {
+				t.Fatalf("unable to create %s offer: %v", src, err)
+			}
+			if err := d.CreateQueryOfferAssoc(ctx, &CreateQueryOfferAssocParams{QueryID: q.ID, OfferSource: src, OfferID: o.ID}); err != nil {
+				t.Fatalf("unable to associate %s offer: %v", src, err)
+			}
+		}
+		after, err := d.ListOffers(ctx, &ListOffersParams{ID: q.ID})
+		if err != nil {
+			t.Fatalf("unable to list offers: %v", err)
+		}
+		if got, want := len(after)-len(before), 2; got != want {
+			t.Errorf("expected %d new offers, got %d", want, got)
+		}
+		o, err := d.GetOffer(ctx, &GetOfferParams{Source: "indeed", ID: "4242"})
+		if err != nil {
+			t.Fatalf("unable to get indeed offer: %v", err)
+		}
+		if o.Company != "indeed GmbH" {
+			t.Errorf("expected the indeed offer, got %s's", o.Company)
+		}
+	})
+}
+
+func TestCreateOffer(t *testing.T) {
+	d, closer := NewTestDB(t)
+	defer closer()
+	ctx := context.Background()
+
+	o := &CreateOfferParams{
+		ID:       "create_1",
+		Title:    "Golang Developer",
+		PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Source:   "linkedin",
+	}
+	inserted, err := d.CreateOffer(ctx, o)
+	if err != nil {
+		t.Fatalf("unable to create offer: %v", err)
+	}
+	if inserted != 1 {
+		t.Errorf("expected the new offer to be inserted, got %d rows", inserted)
+	}
+
+	o.Title = "Senior Golang Developer"
+	inserted, err = d.CreateOffer(ctx, o)
+	if err != nil {
+		t.Fatalf("unable to create duplicated offer: %v", err)
+	}
+	if inserted != 0 {
+		t.Errorf("expected the duplicated offer not to be inserted, got %d rows", inserted)
+	}
+	got, err := d.GetOffer(ctx, &GetOfferParams{Source: o.Source, ID: o.ID})
+	if err != nil {
+		t.Fatalf("unable to get offer: %v", err)
+	}
+	if got.Title != "Golang Developer" {
+		t.Errorf("expected the duplicated offer not to overwrite the existing one, got title %q", got.Title)
+	}
+}
+
+func TestUpsertOffer(t *testing.T) {
+	d, closer := NewTestDB(t)
+	defer closer()
+	ctx := context.Background()
+
+	o := &UpsertOfferParams{
+		ID:          "upsert_1",
+		Title:       "Golang Developer",
+		Company:     "Acme",
+		Salary:      "€70,000.00",
+		Description: "<p>Write Go.</p>",
+		PostedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Source:      "linkedin",
+	}
+	inserted, err := d.UpsertOffer(ctx, o)
+	if err != nil {
+		t.Fatalf("unable to upsert offer: %v", err)
+	}
+	if !inserted {
+		t.Error("expected the new offer to be inserted")
+	}
+	before, err := d.GetOffer(ctx, &GetOfferParams{Source: o.Source, ID: o.ID})
+	if err != nil {
+		t.Fatalf("unable to get offer: %v", err)
+	}
+
+	// Scrapes without descriptions don't clear the stored one.
+	o.Title = "Senior Golang Developer"
+	o.Salary = "€90,000.00"
+	o.Description = ""
+	inserted, err = d.UpsertOffer(ctx, o)
+	if err != nil {
+		t.Fatalf("unable to upsert changed offer: %v", err)
+	}
+	if inserted {
+		t.Error("expected the existing offer to be updated")
+	}
+	got, err := d.GetOffer(ctx, &GetOfferParams{Source: o.Source, ID: o.ID})
+	if err != nil {
+		t.Fatalf("unable to get offer: %v", err)
+	}
+	if got.Title != "Senior Golang Developer" || got.Salary != "€90,000.00" {
+		t.Errorf("expected the changed fields to be updated, got title %q and salary %q", got.Title, got.Salary)
+	}
+	if got.Description != "<p>Write Go.</p>" {
+		t.Errorf("expected the description to be kept, got %q", got.Description)
+	}
+	if !got.CreatedAt.Time.Equal(before.CreatedAt.Time) {
+		t.Errorf("expected created_at to be kept, got %v instead of %v", got.CreatedAt.Time, before.CreatedAt.Time)
+	}
+}
+
+func TestUpdateQueryQATIfStale(t *testing.T) {
+	d, closer := NewTestDB(t)
+	defer closer()
+	ctx := context.Background()
+
+	// The seed's python in san francisco query was last read 8 days ago.
+	q, err := d.GetQuery(ctx, &GetQueryParams{Keywords: "python", Location: "san francisco"})
+	if err != nil {
+		t.Fatalf("unable to get seed query: %v", err)
+	}
+	p := &UpdateQueryQATIfStaleParams{ID: q.ID, StaleAfter: (15 * time.Minute).Seconds()}
+
+	for i, want := range []int64{1, 0} {
+		n, err := d.UpdateQueryQATIfStale(ctx, p)
+		if err != nil {
+			t.Fatalf("unable to update queried_at: %v", err)
+		}
+		if n != want {
+			t.Errorf("expected read %d to update %d rows, got %d", i+1, want, n)
+		}
+	}
+
+	// Without throttle every read updates it.
+	p.StaleAfter = 0
+	if n, err := d.UpdateQueryQATIfStale(ctx, p); err != nil || n != 1 {
+		t.Errorf("expected the unthrottled read to update 1 row, got %d (error: %v)", n, err)
+	}
+}
+
+func TestQueryGroups(t *testing.T) {
+	d, closer := NewTestDB(t)
+	defer closer()
+	ctx := context.Background()
+
+	var ids []int64
+	for _, p := range []*GetQueryParams{{Keywords: "golang", Location: "berlin"}, {Keywords: "retry", Location: "berlin"}} {
+		q, err := d.GetQuery(ctx, p)
+		if err != nil {
+			t.Fatalf("unable to get seeded query: %v", err)
+		}
+		ids = append(ids, q.ID)
+	}
+	memberIDs := fmt.Sprintf("%d,%d", ids[0], ids[1])
+
+	g, err := d.CreateQueryGroup(ctx, memberIDs)
+	if err != nil {
+		t.Fatalf("unable to create query group: %v", err)
+	}
+	for _, id := range append(ids, ids[0]) {
+		if err := d.CreateQueryGroupMember(ctx, &CreateQueryGroupMemberParams{GroupID: g.ID, QueryID: id}); err != nil {
+			t.Fatalf("unable to add query group member: %v", err)
+		}
+	}
+	again, err := d.CreateQueryGroup(ctx, memberIDs)
+	if err != nil {
+		t.Fatalf("unable to create existing query group: %v", err)
+	}
+	if again.ID != g.ID {
+		t.Errorf("expected the existing group %d, got %d", g.ID, again.ID)
+	}
+
+	members, err := d.ListQueryGroupMembers(ctx, g.ID)
+	if err != nil {
+		t.Fatalf("unable to list query group members: %v", err)
+	}
+	var got []int64
+	for _, m := range members {
+		got = append(got, m.ID)
+	}
+	if !slices.Equal(got, ids) {
+		t.Errorf("expected members %v, got %v", ids, got)
+	}
+
+	// Deleting a member deletes its groups.
+	if err := d.DeleteQueryGroupsByQuery(ctx, ids[1]); err != nil {
+		t.Fatalf("unable to delete query groups: %v", err)
+	}
+	if members, err := d.ListQueryGroupMembers(ctx, g.ID); err != nil || len(members) != 0 {
+		t.Errorf("expected the group to be deleted, got %d members (error: %v)", len(members), err)
+	}
+}
+
+func TestCountOffersByCompany(t *testing.T) {
+	d, closer := NewTestDB(t)
+	defer closer()
+	ctx := context.Background()
+
+	q, err := d.GetQuery(ctx, &GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to get seeded query: %v", err)
+	}
+	for _, o := range []*CreateOfferParams{
+		{ID: "company_1", Title: "Golang Developer", Company: "Globex"},
+		{ID: "company_2", Title: "Golang Developer", Company: "Acme"},
+		{ID: "company_3", Title: "Senior Golang Developer", Company: "Acme"},
+	} {
+		o.Source = "linkedin"
+		o.PostedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		if _, err := d.CreateOffer(ctx, o); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+		if err := d.CreateQueryOfferAssoc(ctx, &CreateQueryOfferAssocParams{QueryID: q.ID, OfferSource: o.Source, OfferID: o.ID}); err != nil {
+			t.Fatalf("unable to associate offer: %v", err)
+		}
+	}
+
+	got, err := d.CountOffersByCompany(ctx, q.ID)
+	if err != nil {
+		t.Fatalf("unable to count offers by company: %v", err)
+	}
+	// Companies with the same amount of offers are sorted by name.
+	want := []CountOffersByCompanyRow{
+		{Company: "Acme", OfferCount: 2},
+		{Company: "Globex", OfferCount: 1},
+		{Company: "Späti GmbH", OfferCount: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d companies, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Errorf("expected company %d to be %+v, got %+v", i, want[i], *got[i])
+		}
+	}
+}
+
+func TestSearchOffers(t *testing.T) {
+	d, closer := NewTestDB(t)
+	defer closer()
+	ctx := context.Background()
+
+	for _, o := range []*CreateOfferParams{
+		{ID: "search_1", Title: "Golang Developer", Company: "Acme", Location: "Munich"},
+		{ID: "search_2", Title: "Senior Golang Developer", Company: "Golang Labs", Location: "Hamburg"},
+		{ID: "search_3", Title: "Python Developer", Company: "Snake Corp", Location: "Munich"},
+		{ID: "search_4", Title: "Golang Engineer", Company: "Gophers Inc", Location: "Munich"},
+	} {
+		o.Source = "linkedin"
+		o.PostedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+		if _, err := d.CreateOffer(ctx, o); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []string
+	}{
+		// Matches are ranked by how often the words appear.
+		{name: "multi-word match", query: "golang developer", wantIDs: []string{"search_2", "search_1"}},
+		{name: "matches company and location", query: "labs hamburg", wantIDs: []string{"search_2"}},
+		{name: "case insensitive", query: "PYTHON", wantIDs: []string{"search_3"}},
+		{name: "no matches", query: "cobol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offers, err := d.SearchOffers(ctx, &SearchOffersParams{Query: tt.query, Limit: 10})
+			if err != nil {
+				t.Fatalf("unable to search offers: %v", err)
+			}
+			var gotIDs []string
+			for _, o := range offers {
+				gotIDs = append(gotIDs, o.ID)
+			}
+			if !slices.Equal(tt.wantIDs, gotIDs) {
+				t.Errorf("expected offers %v, got %v", tt.wantIDs, gotIDs)
+			}
+		})
+	}
+}
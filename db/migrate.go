@@ -0,0 +1,177 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// noVersion is the version of a DB without migrations, as golang-migrate stores it.
+const noVersion = -1
+
+// migration is one of the embedded migrations, ie. 000001_add_initial_tables.
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+// migrations returns the embedded migrations, sorted by version.
+func migrations() ([]migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list migrations: %w", err)
+	}
+	byVersion := map[int64]*migration{}
+	for _, f := range files {
+		name, direction, ok := strings.Cut(strings.TrimSuffix(path.Base(f), ".sql"), ".")
+		v, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(v, 10, 64)
+		if !ok || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name: %s", f)
+		}
+		sql, err := migrationFiles.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read migration %s: %w", f, err)
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(sql)
+		} else {
+			m.down = string(sql)
+		}
+	}
+	var ms []migration
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %s is missing its up or down file", m.name)
+		}
+		ms = append(ms, *m)
+	}
+	slices.SortFunc(ms, func(a, b migration) int { return cmp.Compare(a.version, b.version) })
+	return ms, nil
+}
+
+// MigrateUp applies the pending migrations, returning how many it applied.
+// Like golang-migrate, used by `make migrate-up`, it keeps the DB's version
+// in the schema_migrations table, so both can migrate the same DB.
+func MigrateUp(ctx context.Context, conn DBTX) (int, error) {
+	ms, err := migrations()
+	if err != nil {
+		return 0, err
+	}
+	current, err := migrationVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	var applied int
+	for _, m := range ms {
+		if m.version <= current {
+			continue
+		}
+		if err := migrate(ctx, conn, m.up, m.version); err != nil {
+			return applied, fmt.Errorf("unable to apply migration %s: %w", m.name, err)
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the last steps applied migrations, returning how many it reverted.
+func MigrateDown(ctx context.Context, conn DBTX, steps int) (int, error) {
+	ms, err := migrations()
+	if err != nil {
+		return 0, err
+	}
+	current, err := migrationVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	var reverted int
+	for i := len(ms) - 1; i >= 0 && reverted < steps; i-- {
+		if ms[i].version > current {
+			continue
+		}
+		previous := int64(noVersion)
+		if i > 0 {
+			previous = ms[i-1].version
+		}
+		if err := migrate(ctx, conn, ms[i].down, previous); err != nil {
+			return reverted, fmt.Errorf("unable to revert migration %s: %w", ms[i].name, err)
+		}
+		reverted++
+	}
+	return reverted, nil
+}
+
+// migrationVersion returns the version of the last applied migration. A DB
+// left dirty by a failed migration has to be fixed by hand before migrating it.
+func migrationVersion(ctx context.Context, conn DBTX) (int64, error) {
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return 0, fmt.Errorf("unable to create schema_migrations: %w", err)
+	}
+	var (
+		version int64
+		dirty   bool
+	)
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return noVersion, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the migration version: %w", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("the DB is dirty at migration version %d, a migration failed half way", version)
+	}
+	return version, nil
+}
+
+// migrate runs a migration, which has its own transaction, leaving the DB at
+// version. The DB is marked dirty while it runs, so a failure is noticed.
+func migrate(ctx context.Context, conn DBTX, sql string, version int64) error {
+	if err := setMigrationVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+	if _, err := conn.Exec(ctx, sql); err != nil {
+		return err
+	}
+	return setMigrationVersion(ctx, conn, version, false)
+}
+
+func setMigrationVersion(ctx context.Context, conn DBTX, version int64, dirty bool) error {
+	// Without migrations the table is empty, unless one failed reverting the first.
+	if version == noVersion && !dirty {
+		if _, err := conn.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+			return fmt.Errorf("unable to set the migration version: %w", err)
+		}
+		return nil
+	}
+	if _, err := conn.Exec(ctx, `DELETE FROM schema_migrations WHERE version <> $1`, version); err != nil {
+		return fmt.Errorf("unable to set the migration version: %w", err)
+	}
+	_, err := conn.Exec(ctx, `
+INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)
+ON CONFLICT (version) DO UPDATE SET dirty = EXCLUDED.dirty`, version, dirty)
+	if err != nil {
+		return fmt.Errorf("unable to set the migration version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestMigrations(t *testing.T) {
+	ms, err := migrations()
+	if err != nil {
+		t.Fatalf("unable to read the migrations: %v", err)
+	}
+	files, err := filepath.Glob("migrations/*.up.sql")
+	if err != nil {
+		t.Fatalf("unable to list the migration files: %v", err)
+	}
+	if len(ms) != len(files) {
+		t.Fatalf("expected %d migrations, got %d", len(files), len(ms))
+	}
+	for i, m := range ms {
+		if m.version != int64(i+1) {
+			t.Errorf("expected migration %s to have version %d, got %d", m.name, i+1, m.version)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	files, err := filepath.Glob("migrations/*.sql")
+	if err != nil {
+		t.Fatalf("unable to list the migration files: %v", err)
+	}
+	embedded, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		t.Fatalf("unable to list the embedded migrations: %v", err)
+	}
+	if !slices.Equal(files, embedded) {
+		t.Fatalf("expected the embedded migrations %v, got %v", files, embedded)
+	}
+	for _, f := range files {
+		want, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("unable to read %s: %v", f, err)
+		}
+		got, err := migrationFiles.ReadFile(f)
+		if err != nil {
+			t.Fatalf("unable to read the embedded %s: %v", f, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("expected the embedded %s to match the one on disk", f)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	conn, closer := newTestConn(t)
+	defer closer()
+	ctx := context.Background()
+	ms, err := migrations()
+	if err != nil {
+		t.Fatalf("unable to read the migrations: %v", err)
+	}
+	last := ms[len(ms)-1].version
+
+	version := func() int64 {
+		v, err := migrationVersion(ctx, conn)
+		if err != nil {
+			t.Fatalf("unable to get the migration version: %v", err)
+		}
+		return v
+	}
+	// The queries only work once the tables exist.
+	migrated := func() bool {
+		_, err := New(conn).GetQuery(ctx, &GetQueryParams{Keywords: "golang", Location: "berlin"})
+		return errors.Is(err, pgx.ErrNoRows)
+	}
+
+	if n, err := MigrateUp(ctx, conn); err != nil || n != len(ms) {
+		t.Fatalf("expected to apply %d migrations, applied %d (error: %v)", len(ms), n, err)
+	}
+	if v := version(); v != last || !migrated() {
+		t.Errorf("expected the DB migrated to version %d, got %d", last, v)
+	}
+	if n, err := MigrateUp(ctx, conn); err != nil || n != 0 {
+		t.Errorf("expected no pending migrations, applied %d (error: %v)", n, err)
+	}
+
+	if n, err := MigrateDown(ctx, conn, 1); err != nil || n != 1 {
+		t.Fatalf("expected to revert a migration, reverted %d (error: %v)", n, err)
+	}
+	if v := version(); v != last-1 {
+		t.Errorf("expected version %d, got %d", last-1, v)
+	}
+	if n, err := MigrateUp(ctx, conn); err != nil || n != 1 {
+		t.Errorf("expected to apply the reverted migration, applied %d (error: %v)", n, err)
+	}
+
+	if n, err := MigrateDown(ctx, conn, len(ms)+1); err != nil || n != len(ms) {
+		t.Fatalf("expected to revert %d migrations, reverted %d (error: %v)", len(ms), n, err)
+	}
+	if v := version(); v != noVersion || migrated() {
+		t.Errorf("expected the DB without migrations, got version %d", v)
+	}
+}
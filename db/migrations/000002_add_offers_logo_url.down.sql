@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE offers DROP COLUMN IF EXISTS logo_url;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS logo_url TEXT NOT NULL DEFAULT '';
+
+COMMIT;
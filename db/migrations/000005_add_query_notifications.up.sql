@@ -0,0 +1,12 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS query_notifications (
+    query_id BIGINT PRIMARY KEY,
+    webhook_url TEXT NOT NULL,
+    remote_only BOOLEAN NOT NULL DEFAULT FALSE,
+    new_companies_only BOOLEAN NOT NULL DEFAULT FALSE,
+    min_salary INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (query_id) REFERENCES queries (id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE queries ADD COLUMN IF NOT EXISTS interval_hours INTEGER NOT NULL DEFAULT 1; -- How often the query is executed.
+
+COMMIT;
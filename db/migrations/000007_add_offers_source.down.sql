@@ -0,0 +1,14 @@
+BEGIN;
+
+ALTER TABLE query_offers DROP CONSTRAINT IF EXISTS query_offers_offer_fkey;
+ALTER TABLE query_offers DROP CONSTRAINT IF EXISTS query_offers_pkey;
+ALTER TABLE offers DROP CONSTRAINT IF EXISTS offers_pkey;
+
+ALTER TABLE query_offers DROP COLUMN IF EXISTS offer_source;
+ALTER TABLE offers DROP COLUMN IF EXISTS source;
+
+ALTER TABLE offers ADD PRIMARY KEY (id);
+ALTER TABLE query_offers ADD PRIMARY KEY (query_id, offer_id);
+ALTER TABLE query_offers ADD FOREIGN KEY (offer_id) REFERENCES offers (id) ON DELETE CASCADE;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Offers are identified by the portal they come from and their ID in it,
+-- so IDs from different portals can't clash. The offers scraped until
+-- now come from LinkedIn, except the prefixed RemoteOK ones.
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'linkedin';
+UPDATE offers SET source = 'remoteok' WHERE id LIKE 'remoteok-%';
+
+ALTER TABLE query_offers ADD COLUMN IF NOT EXISTS offer_source TEXT NOT NULL DEFAULT 'linkedin';
+UPDATE query_offers SET offer_source = 'remoteok' WHERE offer_id LIKE 'remoteok-%';
+
+ALTER TABLE query_offers DROP CONSTRAINT IF EXISTS query_offers_offer_id_fkey;
+ALTER TABLE query_offers DROP CONSTRAINT IF EXISTS query_offers_pkey;
+ALTER TABLE offers DROP CONSTRAINT IF EXISTS offers_pkey;
+
+ALTER TABLE offers ADD PRIMARY KEY (source, id);
+ALTER TABLE query_offers ADD PRIMARY KEY (query_id, offer_source, offer_id);
+ALTER TABLE query_offers ADD CONSTRAINT query_offers_offer_fkey
+    FOREIGN KEY (offer_source, offer_id) REFERENCES offers (source, id) ON DELETE CASCADE;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+-- The company name as scraped, before applying the company aliases.
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS raw_company TEXT NOT NULL DEFAULT '';
+
+COMMIT;
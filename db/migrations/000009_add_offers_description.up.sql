@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE offers ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS offers_search_idx;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Full-text search over the offers, see SearchOffers. The 'simple' config
+-- doesn't stem, as offers come in many languages.
+CREATE INDEX IF NOT EXISTS offers_search_idx ON offers USING GIN (
+    to_tsvector('simple', title || ' ' || company || ' ' || location)
+);
+
+COMMIT;
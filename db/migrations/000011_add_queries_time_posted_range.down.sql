@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE queries DROP COLUMN IF EXISTS time_posted_range;
+
+COMMIT;
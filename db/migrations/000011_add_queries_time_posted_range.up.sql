@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE queries ADD COLUMN IF NOT EXISTS time_posted_range TEXT NOT NULL DEFAULT 'week'; -- How far back the first scrape looks.
+
+COMMIT;
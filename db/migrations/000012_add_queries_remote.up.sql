@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE queries ADD COLUMN IF NOT EXISTS remote BOOLEAN NOT NULL DEFAULT FALSE; -- Only scrapes remote offers.
+
+COMMIT;
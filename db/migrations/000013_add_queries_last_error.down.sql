@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE queries DROP COLUMN IF EXISTS last_error_at;
+ALTER TABLE queries DROP COLUMN IF EXISTS last_error;
+
+COMMIT;
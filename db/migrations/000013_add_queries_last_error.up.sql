@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE queries ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT ''; -- Error of the last run, empty if it succeeded.
+ALTER TABLE queries ADD COLUMN IF NOT EXISTS last_error_at TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS query_group_members;
+DROP TABLE IF EXISTS query_groups;
+
+COMMIT;
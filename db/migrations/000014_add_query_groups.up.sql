@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS query_groups (
+    id BIGSERIAL PRIMARY KEY,
+    member_ids TEXT NOT NULL UNIQUE, -- Sorted ids of the member queries, so each set of queries has one group.
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS query_group_members (
+    group_id BIGINT NOT NULL,
+    query_id BIGINT NOT NULL,
+    PRIMARY KEY (group_id, query_id),
+    FOREIGN KEY (group_id) REFERENCES query_groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (query_id) REFERENCES queries (id) ON DELETE CASCADE
+);
+
+COMMIT;
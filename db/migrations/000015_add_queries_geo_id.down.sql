@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE queries DROP COLUMN IF EXISTS geo_id;
+
+COMMIT;
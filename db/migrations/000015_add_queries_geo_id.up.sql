@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE queries ADD COLUMN IF NOT EXISTS geo_id TEXT NOT NULL DEFAULT ''; -- LinkedIn's ID of the location, empty to search it by name.
+
+COMMIT;
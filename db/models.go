@@ -9,24 +9,56 @@ import (
 )
 
 type Offer struct {
-	ID        string
-	Title     string
-	Company   string
-	Location  string
-	PostedAt  pgtype.Timestamptz
-	CreatedAt pgtype.Timestamptz
+	ID          string
+	Title       string
+	Company     string
+	Location    string
+	PostedAt    pgtype.Timestamptz
+	CreatedAt   pgtype.Timestamptz
+	LogoUrl     string
+	Salary      string
+	Url         string
+	Source      string
+	RawCompany  string
+	Description string
 }
 
 type Query struct {
+	ID              int64
+	Keywords        string
+	Location        string
+	CreatedAt       pgtype.Timestamptz
+	QueriedAt       pgtype.Timestamptz
+	UpdatedAt       pgtype.Timestamptz
+	IntervalHours   int32
+	TimePostedRange string
+	Remote          bool
+	LastError       string
+	LastErrorAt     pgtype.Timestamptz
+	GeoID           string
+}
+
+type QueryGroup struct {
 	ID        int64
-	Keywords  string
-	Location  string
+	MemberIds string
 	CreatedAt pgtype.Timestamptz
-	QueriedAt pgtype.Timestamptz
-	UpdatedAt pgtype.Timestamptz
 }
 
-type QueryOffer struct {
+type QueryGroupMember struct {
+	GroupID int64
 	QueryID int64
-	OfferID string
+}
+
+type QueryNotification struct {
+	QueryID          int64
+	WebhookUrl       string
+	RemoteOnly       bool
+	NewCompaniesOnly bool
+	MinSalary        int32
+}
+
+type QueryOffer struct {
+	QueryID     int64
+	OfferID     string
+	OfferSource string
 }
@@ -1,8 +1,8 @@
 -- name: CreateQuery :one
 INSERT INTO
-    queries (keywords, location)
+    queries (keywords, location, interval_hours, time_posted_range, remote, geo_id)
 VALUES
-    ($1, $2) RETURNING *;
+    ($1, $2, $3, $4, $5, $6) RETURNING *;
 
 -- name: ListQueries :many
 SELECT
@@ -10,6 +10,19 @@ SELECT
 FROM
     queries;
 
+-- name: ListPopularQueries :many
+-- Queries read in the last 7 days, the most recently read first.
+SELECT
+    *
+FROM
+    queries
+WHERE
+    queried_at > NOW() - INTERVAL '7 days'
+ORDER BY
+    queried_at DESC,
+    created_at DESC
+LIMIT $1;
+
 -- name: GetQuery :one
 SELECT
     *
@@ -39,35 +52,200 @@ SET
 WHERE
     id = $1;
 
+-- name: UpdateQueryQATIfStale :execrows
+-- Feed readers poll often, so queried_at is only written once it's older than stale_after seconds.
+UPDATE queries
+SET
+    queried_at = CURRENT_TIMESTAMP
+WHERE
+    id = @id
+    AND queried_at < NOW() - make_interval(secs => @stale_after::FLOAT8);
+
 -- name: UpdateQueryUAT :exec
+-- A successful run clears the error of the previous ones.
 UPDATE queries
 SET
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    last_error = '',
+    last_error_at = NULL
 WHERE
     id = $1;
 
--- name: CreateOffer :exec
-INSERT INTO offers (id, title, company, location, posted_at)
-VALUES ($1, $2, $3, $4, $5)
-ON CONFLICT (id) DO NOTHING;
+-- name: UpdateQueryError :exec
+UPDATE queries
+SET
+    last_error = $2,
+    last_error_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1;
+
+-- name: CreateOffer :execrows
+INSERT INTO offers (id, title, company, location, posted_at, logo_url, salary, url, source, raw_company, description)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (source, id) DO NOTHING;
+
+-- name: UpsertOffer :one
+-- Existing offers get their changing fields updated, keeping their creation
+-- time, and their description when the scrape didn't fetch it. It returns
+-- whether the offer was inserted rather than updated.
+INSERT INTO offers (id, title, company, location, posted_at, logo_url, salary, url, source, raw_company, description)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (source, id) DO UPDATE
+SET
+    title = EXCLUDED.title,
+    company = EXCLUDED.company,
+    raw_company = EXCLUDED.raw_company,
+    location = EXCLUDED.location,
+    salary = EXCLUDED.salary,
+    description = COALESCE(NULLIF(EXCLUDED.description, ''), offers.description)
+RETURNING (xmax = 0) AS inserted;
+
+-- name: GetOffer :one
+SELECT
+    *
+FROM
+    offers
+WHERE
+    source = $1
+    AND id = $2;
 
 -- name: ListOffers :many
+-- A zero limit lists all the offers.
 SELECT
     o.*
 FROM
     queries q
     JOIN query_offers qo ON q.id = qo.query_id
-    JOIN offers o ON qo.offer_id = o.id
+    JOIN offers o ON qo.offer_source = o.source
+    AND qo.offer_id = o.id
 WHERE
-    q.id = $1
+    q.id = @id
 ORDER BY
-    o.posted_at DESC;
+    o.posted_at DESC,
+    o.source,
+    o.id
+LIMIT NULLIF(sqlc.arg('limit')::INT, 0) OFFSET sqlc.arg('offset')::INT;
+
+-- name: SearchOffers :many
+-- Offers matching all the words of the query in their title, company or
+-- location, the most relevant first. The expression matches offers_search_idx.
+SELECT
+    o.*
+FROM
+    offers o
+WHERE
+    to_tsvector('simple', o.title || ' ' || o.company || ' ' || o.location) @@ plainto_tsquery('simple', sqlc.arg('query')::TEXT)
+ORDER BY
+    ts_rank(
+        to_tsvector('simple', o.title || ' ' || o.company || ' ' || o.location),
+        plainto_tsquery('simple', sqlc.arg('query')::TEXT)
+    ) DESC,
+    o.posted_at DESC
+LIMIT sqlc.arg('limit')::INT;
+
+-- name: CountOffers :one
+SELECT
+    COUNT(*)
+FROM
+    query_offers
+WHERE
+    query_id = $1;
+
+-- name: CountOffersByCompany :many
+-- The query's offers per company, the companies with more offers first.
+SELECT
+    o.company,
+    COUNT(*) AS offer_count
+FROM
+    query_offers qo
+    JOIN offers o ON qo.offer_source = o.source
+    AND qo.offer_id = o.id
+WHERE
+    qo.query_id = $1
+GROUP BY
+    o.company
+ORDER BY
+    offer_count DESC,
+    o.company;
 
 -- name: CreateQueryOfferAssoc :exec
-INSERT INTO query_offers (query_id, offer_id)
-VALUES ($1, $2)
-ON CONFLICT (query_id, offer_id) DO NOTHING;
+INSERT INTO query_offers (query_id, offer_source, offer_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (query_id, offer_source, offer_id) DO NOTHING;
 
 -- name: DeleteOldOffers :exec
-DELETE FROM offers
-WHERE posted_at < NOW() - INTERVAL '7 days';
+-- Retentions are in seconds. Sources without their own retention use the default one.
+DELETE FROM offers o
+WHERE o.posted_at < NOW() - make_interval(secs => COALESCE(
+    (
+        SELECT r.retention
+        FROM UNNEST(@sources::TEXT[], @retentions::FLOAT8[]) AS r (source, retention)
+        WHERE r.source = o.source
+    ),
+    @default_retention::FLOAT8
+));
+
+-- name: CountOffersPerQuery :many
+SELECT
+    COUNT(qo.offer_id) AS offer_count
+FROM
+    queries q
+    LEFT JOIN query_offers qo ON q.id = qo.query_id
+GROUP BY
+    q.id;
+
+-- name: UpsertQueryNotification :exec
+INSERT INTO query_notifications (query_id, webhook_url, remote_only, new_companies_only, min_salary)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (query_id) DO UPDATE
+SET
+    webhook_url = EXCLUDED.webhook_url,
+    remote_only = EXCLUDED.remote_only,
+    new_companies_only = EXCLUDED.new_companies_only,
+    min_salary = EXCLUDED.min_salary;
+
+-- name: GetQueryNotification :one
+SELECT
+    *
+FROM
+    query_notifications
+WHERE
+    query_id = $1;
+
+-- name: CreateQueryGroup :one
+-- Creating an existing group returns it.
+INSERT INTO query_groups (member_ids)
+VALUES ($1)
+ON CONFLICT (member_ids) DO UPDATE
+SET
+    member_ids = EXCLUDED.member_ids
+RETURNING *;
+
+-- name: CreateQueryGroupMember :exec
+INSERT INTO query_group_members (group_id, query_id)
+VALUES ($1, $2)
+ON CONFLICT (group_id, query_id) DO NOTHING;
+
+-- name: ListQueryGroupMembers :many
+SELECT
+    q.*
+FROM
+    queries q
+    JOIN query_group_members m ON q.id = m.query_id
+WHERE
+    m.group_id = $1
+ORDER BY
+    q.id;
+
+-- name: DeleteQueryGroupsByQuery :exec
+-- A group's feed is incomplete without any of its queries, so it goes with them.
+DELETE FROM query_groups
+WHERE
+    id IN (
+        SELECT
+            group_id
+        FROM
+            query_group_members
+        WHERE
+            query_id = $1
+    );
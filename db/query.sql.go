@@ -11,45 +11,160 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const createOffer = `-- name: CreateOffer :exec
-INSERT INTO offers (id, title, company, location, posted_at)
-VALUES ($1, $2, $3, $4, $5)
-ON CONFLICT (id) DO NOTHING
+const countOffers = `-- name: CountOffers :one
+SELECT
+    COUNT(*)
+FROM
+    query_offers
+WHERE
+    query_id = $1
+`
+
+func (q *Queries) CountOffers(ctx context.Context, queryID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, countOffers, queryID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countOffersByCompany = `-- name: CountOffersByCompany :many
+SELECT
+    o.company,
+    COUNT(*) AS offer_count
+FROM
+    query_offers qo
+    JOIN offers o ON qo.offer_source = o.source
+    AND qo.offer_id = o.id
+WHERE
+    qo.query_id = $1
+GROUP BY
+    o.company
+ORDER BY
+    offer_count DESC,
+    o.company
+`
+
+type CountOffersByCompanyRow struct {
+	Company    string
+	OfferCount int64
+}
+
+// The query's offers per company, the companies with more offers first.
+func (q *Queries) CountOffersByCompany(ctx context.Context, queryID int64) ([]*CountOffersByCompanyRow, error) {
+	rows, err := q.db.Query(ctx, countOffersByCompany, queryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*CountOffersByCompanyRow
+	for rows.Next() {
+		var i CountOffersByCompanyRow
+		if err := rows.Scan(&i.Company, &i.OfferCount); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countOffersPerQuery = `-- name: CountOffersPerQuery :many
+SELECT
+    COUNT(qo.offer_id) AS offer_count
+FROM
+    queries q
+    LEFT JOIN query_offers qo ON q.id = qo.query_id
+GROUP BY
+    q.id
+`
+
+func (q *Queries) CountOffersPerQuery(ctx context.Context) ([]int64, error) {
+	rows, err := q.db.Query(ctx, countOffersPerQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var offer_count int64
+		if err := rows.Scan(&offer_count); err != nil {
+			return nil, err
+		}
+		items = append(items, offer_count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOffer = `-- name: CreateOffer :execrows
+INSERT INTO offers (id, title, company, location, posted_at, logo_url, salary, url, source, raw_company, description)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (source, id) DO NOTHING
 `
 
 type CreateOfferParams struct {
-	ID       string
-	Title    string
-	Company  string
-	Location string
-	PostedAt pgtype.Timestamptz
+	ID          string
+	Title       string
+	Company     string
+	Location    string
+	PostedAt    pgtype.Timestamptz
+	LogoUrl     string
+	Salary      string
+	Url         string
+	Source      string
+	RawCompany  string
+	Description string
 }
 
-func (q *Queries) CreateOffer(ctx context.Context, arg *CreateOfferParams) error {
-	_, err := q.db.Exec(ctx, createOffer,
+func (q *Queries) CreateOffer(ctx context.Context, arg *CreateOfferParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createOffer,
 		arg.ID,
 		arg.Title,
 		arg.Company,
 		arg.Location,
 		arg.PostedAt,
+		arg.LogoUrl,
+		arg.Salary,
+		arg.Url,
+		arg.Source,
+		arg.RawCompany,
+		arg.Description,
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const createQuery = `-- name: CreateQuery :one
 INSERT INTO
-    queries (keywords, location)
+    queries (keywords, location, interval_hours, time_posted_range, remote, geo_id)
 VALUES
-    ($1, $2) RETURNING id, keywords, location, created_at, queried_at, updated_at
+    ($1, $2, $3, $4, $5, $6) RETURNING id, keywords, location, created_at, queried_at, updated_at, interval_hours, time_posted_range, remote, last_error, last_error_at, geo_id
 `
 
 type CreateQueryParams struct {
-	Keywords string
-	Location string
+	Keywords        string
+	Location        string
+	IntervalHours   int32
+	TimePostedRange string
+	Remote          bool
+	GeoID           string
 }
 
 func (q *Queries) CreateQuery(ctx context.Context, arg *CreateQueryParams) (*Query, error) {
-	row := q.db.QueryRow(ctx, createQuery, arg.Keywords, arg.Location)
+	row := q.db.QueryRow(ctx, createQuery,
+		arg.Keywords,
+		arg.Location,
+		arg.IntervalHours,
+		arg.TimePostedRange,
+		arg.Remote,
+		arg.GeoID,
+	)
 	var i Query
 	err := row.Scan(
 		&i.ID,
@@ -58,33 +173,87 @@ func (q *Queries) CreateQuery(ctx context.Context, arg *CreateQueryParams) (*Que
 		&i.CreatedAt,
 		&i.QueriedAt,
 		&i.UpdatedAt,
+		&i.IntervalHours,
+		&i.TimePostedRange,
+		&i.Remote,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.GeoID,
 	)
 	return &i, err
 }
 
-const createQueryOfferAssoc = `-- name: CreateQueryOfferAssoc :exec
-INSERT INTO query_offers (query_id, offer_id)
+const createQueryGroup = `-- name: CreateQueryGroup :one
+INSERT INTO query_groups (member_ids)
+VALUES ($1)
+ON CONFLICT (member_ids) DO UPDATE
+SET
+    member_ids = EXCLUDED.member_ids
+RETURNING id, member_ids, created_at
+`
+
+// Creating an existing group returns it.
+func (q *Queries) CreateQueryGroup(ctx context.Context, memberIds string) (*QueryGroup, error) {
+	row := q.db.QueryRow(ctx, createQueryGroup, memberIds)
+	var i QueryGroup
+	err := row.Scan(&i.ID, &i.MemberIds, &i.CreatedAt)
+	return &i, err
+}
+
+const createQueryGroupMember = `-- name: CreateQueryGroupMember :exec
+INSERT INTO query_group_members (group_id, query_id)
 VALUES ($1, $2)
-ON CONFLICT (query_id, offer_id) DO NOTHING
+ON CONFLICT (group_id, query_id) DO NOTHING
 `
 
-type CreateQueryOfferAssocParams struct {
+type CreateQueryGroupMemberParams struct {
+	GroupID int64
 	QueryID int64
-	OfferID string
+}
+
+func (q *Queries) CreateQueryGroupMember(ctx context.Context, arg *CreateQueryGroupMemberParams) error {
+	_, err := q.db.Exec(ctx, createQueryGroupMember, arg.GroupID, arg.QueryID)
+	return err
+}
+
+const createQueryOfferAssoc = `-- name: CreateQueryOfferAssoc :exec
+INSERT INTO query_offers (query_id, offer_source, offer_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (query_id, offer_source, offer_id) DO NOTHING
+`
+
+type CreateQueryOfferAssocParams struct {
+	QueryID     int64
+	OfferSource string
+	OfferID     string
 }
 
 func (q *Queries) CreateQueryOfferAssoc(ctx context.Context, arg *CreateQueryOfferAssocParams) error {
-	_, err := q.db.Exec(ctx, createQueryOfferAssoc, arg.QueryID, arg.OfferID)
+	_, err := q.db.Exec(ctx, createQueryOfferAssoc, arg.QueryID, arg.OfferSource, arg.OfferID)
 	return err
 }
 
 const deleteOldOffers = `-- name: DeleteOldOffers :exec
-DELETE FROM offers
-WHERE posted_at < NOW() - INTERVAL '7 days'
+DELETE FROM offers o
+WHERE o.posted_at < NOW() - make_interval(secs => COALESCE(
+    (
+        SELECT r.retention
+        FROM UNNEST($1::TEXT[], $2::FLOAT8[]) AS r (source, retention)
+        WHERE r.source = o.source
+    ),
+    $3::FLOAT8
+))
 `
 
-func (q *Queries) DeleteOldOffers(ctx context.Context) error {
-	_, err := q.db.Exec(ctx, deleteOldOffers)
+type DeleteOldOffersParams struct {
+	Sources          []string
+	Retentions       []float64
+	DefaultRetention float64
+}
+
+// Retentions are in seconds. Sources without their own retention use the default one.
+func (q *Queries) DeleteOldOffers(ctx context.Context, arg *DeleteOldOffersParams) error {
+	_, err := q.db.Exec(ctx, deleteOldOffers, arg.Sources, arg.Retentions, arg.DefaultRetention)
 	return err
 }
 
@@ -99,9 +268,63 @@ func (q *Queries) DeleteQuery(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteQueryGroupsByQuery = `-- name: DeleteQueryGroupsByQuery :exec
+DELETE FROM query_groups
+WHERE
+    id IN (
+        SELECT
+            group_id
+        FROM
+            query_group_members
+        WHERE
+            query_id = $1
+    )
+`
+
+// A group's feed is incomplete without any of its queries, so it goes with them.
+func (q *Queries) DeleteQueryGroupsByQuery(ctx context.Context, queryID int64) error {
+	_, err := q.db.Exec(ctx, deleteQueryGroupsByQuery, queryID)
+	return err
+}
+
+const getOffer = `-- name: GetOffer :one
+SELECT
+    id, title, company, location, posted_at, created_at, logo_url, salary, url, source, raw_company, description
+FROM
+    offers
+WHERE
+    source = $1
+    AND id = $2
+`
+
+type GetOfferParams struct {
+	Source string
+	ID     string
+}
+
+func (q *Queries) GetOffer(ctx context.Context, arg *GetOfferParams) (*Offer, error) {
+	row := q.db.QueryRow(ctx, getOffer, arg.Source, arg.ID)
+	var i Offer
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Company,
+		&i.Location,
+		&i.PostedAt,
+		&i.CreatedAt,
+		&i.LogoUrl,
+		&i.Salary,
+		&i.Url,
+		&i.Source,
+		&i.RawCompany,
+		&i.Description,
+	)
+	return &i, err
+}
+
 const getQuery = `-- name: GetQuery :one
 SELECT
-    id, keywords, location, created_at, queried_at, updated_at
+    id, keywords, location, created_at, queried_at, updated_at, interval_hours, time_posted_range, remote, last_error, last_error_at, geo_id
 FROM
     queries
 WHERE
@@ -124,13 +347,19 @@ func (q *Queries) GetQuery(ctx context.Context, arg *GetQueryParams) (*Query, er
 		&i.CreatedAt,
 		&i.QueriedAt,
 		&i.UpdatedAt,
+		&i.IntervalHours,
+		&i.TimePostedRange,
+		&i.Remote,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.GeoID,
 	)
 	return &i, err
 }
 
 const getQueryByID = `-- name: GetQueryByID :one
 SELECT
-    id, keywords, location, created_at, queried_at, updated_at
+    id, keywords, location, created_at, queried_at, updated_at, interval_hours, time_posted_range, remote, last_error, last_error_at, geo_id
 FROM
     queries
 WHERE
@@ -147,25 +376,64 @@ func (q *Queries) GetQueryByID(ctx context.Context, id int64) (*Query, error) {
 		&i.CreatedAt,
 		&i.QueriedAt,
 		&i.UpdatedAt,
+		&i.IntervalHours,
+		&i.TimePostedRange,
+		&i.Remote,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.GeoID,
+	)
+	return &i, err
+}
+
+const getQueryNotification = `-- name: GetQueryNotification :one
+SELECT
+    query_id, webhook_url, remote_only, new_companies_only, min_salary
+FROM
+    query_notifications
+WHERE
+    query_id = $1
+`
+
+func (q *Queries) GetQueryNotification(ctx context.Context, queryID int64) (*QueryNotification, error) {
+	row := q.db.QueryRow(ctx, getQueryNotification, queryID)
+	var i QueryNotification
+	err := row.Scan(
+		&i.QueryID,
+		&i.WebhookUrl,
+		&i.RemoteOnly,
+		&i.NewCompaniesOnly,
+		&i.MinSalary,
 	)
 	return &i, err
 }
 
 const listOffers = `-- name: ListOffers :many
 SELECT
-    o.id, o.title, o.company, o.location, o.posted_at, o.created_at
+    o.id, o.title, o.company, o.location, o.posted_at, o.created_at, o.logo_url, o.salary, o.url, o.source, o.raw_company, o.description
 FROM
     queries q
     JOIN query_offers qo ON q.id = qo.query_id
-    JOIN offers o ON qo.offer_id = o.id
+    JOIN offers o ON qo.offer_source = o.source
+    AND qo.offer_id = o.id
 WHERE
     q.id = $1
 ORDER BY
-    o.posted_at DESC
+    o.posted_at DESC,
+    o.source,
+    o.id
+LIMIT NULLIF($2::INT, 0) OFFSET $3::INT
 `
 
-func (q *Queries) ListOffers(ctx context.Context, id int64) ([]*Offer, error) {
-	rows, err := q.db.Query(ctx, listOffers, id)
+type ListOffersParams struct {
+	ID     int64
+	Limit  int32
+	Offset int32
+}
+
+// A zero limit lists all the offers.
+func (q *Queries) ListOffers(ctx context.Context, arg *ListOffersParams) ([]*Offer, error) {
+	rows, err := q.db.Query(ctx, listOffers, arg.ID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -180,6 +448,59 @@ func (q *Queries) ListOffers(ctx context.Context, id int64) ([]*Offer, error) {
 			&i.Location,
 			&i.PostedAt,
 			&i.CreatedAt,
+			&i.LogoUrl,
+			&i.Salary,
+			&i.Url,
+			&i.Source,
+			&i.RawCompany,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPopularQueries = `-- name: ListPopularQueries :many
+SELECT
+    id, keywords, location, created_at, queried_at, updated_at, interval_hours, time_posted_range, remote, last_error, last_error_at, geo_id
+FROM
+    queries
+WHERE
+    queried_at > NOW() - INTERVAL '7 days'
+ORDER BY
+    queried_at DESC,
+    created_at DESC
+LIMIT $1
+`
+
+// Queries read in the last 7 days, the most recently read first.
+func (q *Queries) ListPopularQueries(ctx context.Context, limit int32) ([]*Query, error) {
+	rows, err := q.db.Query(ctx, listPopularQueries, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*Query
+	for rows.Next() {
+		var i Query
+		if err := rows.Scan(
+			&i.ID,
+			&i.Keywords,
+			&i.Location,
+			&i.CreatedAt,
+			&i.QueriedAt,
+			&i.UpdatedAt,
+			&i.IntervalHours,
+			&i.TimePostedRange,
+			&i.Remote,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.GeoID,
 		); err != nil {
 			return nil, err
 		}
@@ -193,7 +514,7 @@ func (q *Queries) ListOffers(ctx context.Context, id int64) ([]*Offer, error) {
 
 const listQueries = `-- name: ListQueries :many
 SELECT
-    id, keywords, location, created_at, queried_at, updated_at
+    id, keywords, location, created_at, queried_at, updated_at, interval_hours, time_posted_range, remote, last_error, last_error_at, geo_id
 FROM
     queries
 `
@@ -214,6 +535,113 @@ func (q *Queries) ListQueries(ctx context.Context) ([]*Query, error) {
 			&i.CreatedAt,
 			&i.QueriedAt,
 			&i.UpdatedAt,
+			&i.IntervalHours,
+			&i.TimePostedRange,
+			&i.Remote,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.GeoID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQueryGroupMembers = `-- name: ListQueryGroupMembers :many
+SELECT
+    q.id, q.keywords, q.location, q.created_at, q.queried_at, q.updated_at, q.interval_hours, q.time_posted_range, q.remote, q.last_error, q.last_error_at, q.geo_id
+FROM
+    queries q
+    JOIN query_group_members m ON q.id = m.query_id
+WHERE
+    m.group_id = $1
+ORDER BY
+    q.id
+`
+
+func (q *Queries) ListQueryGroupMembers(ctx context.Context, groupID int64) ([]*Query, error) {
+	rows, err := q.db.Query(ctx, listQueryGroupMembers, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*Query
+	for rows.Next() {
+		var i Query
+		if err := rows.Scan(
+			&i.ID,
+			&i.Keywords,
+			&i.Location,
+			&i.CreatedAt,
+			&i.QueriedAt,
+			&i.UpdatedAt,
+			&i.IntervalHours,
+			&i.TimePostedRange,
+			&i.Remote,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.GeoID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchOffers = `-- name: SearchOffers :many
+SELECT
+    o.id, o.title, o.company, o.location, o.posted_at, o.created_at, o.logo_url, o.salary, o.url, o.source, o.raw_company, o.description
+FROM
+    offers o
+WHERE
+    to_tsvector('simple', o.title || ' ' || o.company || ' ' || o.location) @@ plainto_tsquery('simple', $1::TEXT)
+ORDER BY
+    ts_rank(
+        to_tsvector('simple', o.title || ' ' || o.company || ' ' || o.location),
+        plainto_tsquery('simple', $1::TEXT)
+    ) DESC,
+    o.posted_at DESC
+LIMIT $2::INT
+`
+
+type SearchOffersParams struct {
+	Query string
+	Limit int32
+}
+
+// Offers matching all the words of the query in their title, company or
+// location, the most relevant first. The expression matches offers_search_idx.
+func (q *Queries) SearchOffers(ctx context.Context, arg *SearchOffersParams) ([]*Offer, error) {
+	rows, err := q.db.Query(ctx, searchOffers, arg.Query, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*Offer
+	for rows.Next() {
+		var i Offer
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Company,
+			&i.Location,
+			&i.PostedAt,
+			&i.CreatedAt,
+			&i.LogoUrl,
+			&i.Salary,
+			&i.Url,
+			&i.Source,
+			&i.RawCompany,
+			&i.Description,
 		); err != nil {
 			return nil, err
 		}
@@ -225,6 +653,25 @@ func (q *Queries) ListQueries(ctx context.Context) ([]*Query, error) {
 	return items, nil
 }
 
+const updateQueryError = `-- name: UpdateQueryError :exec
+UPDATE queries
+SET
+    last_error = $2,
+    last_error_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+`
+
+type UpdateQueryErrorParams struct {
+	ID        int64
+	LastError string
+}
+
+func (q *Queries) UpdateQueryError(ctx context.Context, arg *UpdateQueryErrorParams) error {
+	_, err := q.db.Exec(ctx, updateQueryError, arg.ID, arg.LastError)
+	return err
+}
+
 const updateQueryQAT = `-- name: UpdateQueryQAT :exec
 UPDATE queries
 SET
@@ -238,15 +685,121 @@ func (q *Queries) UpdateQueryQAT(ctx context.Context, id int64) error {
 	return err
 }
 
+const updateQueryQATIfStale = `-- name: UpdateQueryQATIfStale :execrows
+UPDATE queries
+SET
+    queried_at = CURRENT_TIMESTAMP
+WHERE
+    id = $1
+    AND queried_at < NOW() - make_interval(secs => $2::FLOAT8)
+`
+
+type UpdateQueryQATIfStaleParams struct {
+	ID         int64
+	StaleAfter float64
+}
+
+// Feed readers poll often, so queried_at is only written once it's older than stale_after seconds.
+func (q *Queries) UpdateQueryQATIfStale(ctx context.Context, arg *UpdateQueryQATIfStaleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateQueryQATIfStale, arg.ID, arg.StaleAfter)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateQueryUAT = `-- name: UpdateQueryUAT :exec
 UPDATE queries
 SET
-    updated_at = CURRENT_TIMESTAMP
+    updated_at = CURRENT_TIMESTAMP,
+    last_error = '',
+    last_error_at = NULL
 WHERE
     id = $1
 `
 
+// A successful run clears the error of the previous ones.
 func (q *Queries) UpdateQueryUAT(ctx context.Context, id int64) error {
 	_, err := q.db.Exec(ctx, updateQueryUAT, id)
 	return err
 }
+
+const upsertOffer = `-- name: UpsertOffer :one
+INSERT INTO offers (id, title, company, location, posted_at, logo_url, salary, url, source, raw_company, description)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (source, id) DO UPDATE
+SET
+    title = EXCLUDED.title,
+    company = EXCLUDED.company,
+    raw_company = EXCLUDED.raw_company,
+    location = EXCLUDED.location,
+    salary = EXCLUDED.salary,
+    description = COALESCE(NULLIF(EXCLUDED.description, ''), offers.description)
+RETURNING (xmax = 0) AS inserted
+`
+
+type UpsertOfferParams struct {
+	ID          string
+	Title       string
+	Company     string
+	Location    string
+	PostedAt    pgtype.Timestamptz
+	LogoUrl     string
+	Salary      string
+	Url         string
+	Source      string
+	RawCompany  string
+	Description string
+}
+
+// Existing offers get their changing fields updated, keeping their creation
+// time, and their description when the scrape didn't fetch it. It returns
+// whether the offer was inserted rather than updated.
+func (q *Queries) UpsertOffer(ctx context.Context, arg *UpsertOfferParams) (bool, error) {
+	row := q.db.QueryRow(ctx, upsertOffer,
+		arg.ID,
+		arg.Title,
+		arg.Company,
+		arg.Location,
+		arg.PostedAt,
+		arg.LogoUrl,
+		arg.Salary,
+		arg.Url,
+		arg.Source,
+		arg.RawCompany,
+		arg.Description,
+	)
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, err
+}
+
+const upsertQueryNotification = `-- name: UpsertQueryNotification :exec
+INSERT INTO query_notifications (query_id, webhook_url, remote_only, new_companies_only, min_salary)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (query_id) DO UPDATE
+SET
+    webhook_url = EXCLUDED.webhook_url,
+    remote_only = EXCLUDED.remote_only,
+    new_companies_only = EXCLUDED.new_companies_only,
+    min_salary = EXCLUDED.min_salary
+`
+
+type UpsertQueryNotificationParams struct {
+	QueryID          int64
+	WebhookUrl       string
+	RemoteOnly       bool
+	NewCompaniesOnly bool
+	MinSalary        int32
+}
+
+func (q *Queries) UpsertQueryNotification(ctx context.Context, arg *UpsertQueryNotificationParams) error {
+	_, err := q.db.Exec(ctx, upsertQueryNotification,
+		arg.QueryID,
+		arg.WebhookUrl,
+		arg.RemoteOnly,
+		arg.NewCompaniesOnly,
+		arg.MinSalary,
+	)
+	return err
+}
@@ -2,7 +2,6 @@ package db
 
 import (
 	"context"
-	"path/filepath"
 	"testing"
 	"time"
 
@@ -29,6 +28,25 @@ INSERT INTO query_offers (query_id, offer_id) VALUES
 `
 
 func NewTestDB(t testing.TB) (*Queries, func()) {
+	t.Helper()
+	conn, closer := newTestConn(t)
+
+	// The migrations are applied from the embedded files, like in production.
+	if _, err := MigrateUp(context.Background(), conn); err != nil {
+		closer()
+		t.Fatalf("unable to migrate DB: %v", err)
+	}
+	_, err := conn.Exec(context.Background(), seed)
+	if err != nil {
+		t.Fatalf("unable to seed DB: %v", err)
+	}
+
+	return New(conn), closer
+}
+
+// newTestConn starts an empty DB container and returns
+// its connection and the closer terminating it.
+func newTestConn(t testing.TB) (*pgxpool.Pool, func()) {
 	t.Helper()
 	ctx := context.Background()
 
@@ -41,7 +59,6 @@ func NewTestDB(t testing.TB) (*Queries, func()) {
 	postgresContainer, err := postgres.Run(ctx,
 		dbImage,
 		postgres.WithDatabase(dbName),
-		postgres.WithInitScripts(fetchMigrationFiles(t)...),
 		testcontainers.WithWaitStrategy(
 			wait.ForListeningPort(dbPort)),
 	)
@@ -72,24 +89,10 @@ func NewTestDB(t testing.TB) (*Queries, func()) {
 		t.Fatalf("unable to ping the DB: %v", pingErr)
 	}
 
-	_, err = conn.Exec(ctx, seed)
-	if err != nil {
-		t.Fatalf("unable to seed DB: %v", err)
-	}
-
-	return New(conn), func() {
+	return conn, func() {
 		conn.Close()
 		if err := testcontainers.TerminateContainer(postgresContainer); err != nil {
 			t.Errorf("failed to terminate container: %s", err)
 		}
 	}
 }
-
-func fetchMigrationFiles(t testing.TB) []string {
-	t.Helper()
-	files, err := filepath.Glob("../db/migrations/*.up.sql")
-	if err != nil {
-		t.Fatalf("unable to read sql files: %v", err)
-	}
-	return files
-}
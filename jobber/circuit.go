@@ -0,0 +1,121 @@
+package jobber
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alwedo/jobber/metrics"
+)
+
+const (
+	defaultCircuitThreshold = 0.5
+	defaultCircuitWindow    = time.Hour
+	defaultCircuitCooldown  = 30 * time.Minute
+	// circuitMinRuns is the amount of runs within the window needed
+	// to trip the circuit, so a single failure doesn't pause a portal.
+	circuitMinRuns = 5
+)
+
+// WithCircuit sets the failed scrapes rate, over the window, that pauses
+// scraping a portal for the cooldown. Defaults to 50% over an hour, pausing
+// it for 30 minutes. A threshold of 0 disables the circuit.
+func WithCircuit(threshold float64, window, cooldown time.Duration) Option {
+	return func(j *Jobber) {
+		j.circuit = newCircuit(threshold, window, cooldown)
+	}
+}
+
+// circuit pauses scraping a portal when too many of its scrapes fail,
+// so we don't deepen a block by insisting. Once the cooldown is over a
+// single probe run is let through, resuming scraping if it succeeds
+// or pausing it for another cooldown if it doesn't.
+type circuit struct {
+	threshold float64
+	window    time.Duration
+	cooldown  time.Duration
+
+	mu      sync.Mutex
+	portals map[string]*portalCircuit
+}
+
+type portalCircuit struct {
+	runs        []circuitRun
+	pausedUntil time.Time
+	probing     bool
+}
+
+type circuitRun struct {
+	at     time.Time
+	failed bool
+}
+
+func newCircuit(threshold float64, window, cooldown time.Duration) *circuit {
+	return &circuit{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		portals:   make(map[string]*portalCircuit),
+	}
+}
+
+func (c *circuit) portal(name string) *portalCircuit {
+	p, ok := c.portals[name]
+	if !ok {
+		p = &portalCircuit{}
+		c.portals[name] = p
+	}
+	return p
+}
+
+// allow reports whether the portal can be scraped.
+func (c *circuit) allow(portal string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.portal(portal)
+	if p.pausedUntil.IsZero() {
+		return true
+	}
+	if p.probing || time.Now().Before(p.pausedUntil) {
+		return false
+	}
+	p.probing = true
+	return true
+}
+
+// record adds the outcome of an allowed scrape of the portal.
+func (c *circuit) record(portal string, failed bool) {
+	if c.threshold <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.portal(portal)
+	now := time.Now()
+
+	if p.probing {
+		p.probing = false
+		if failed {
+			p.pausedUntil = now.Add(c.cooldown)
+			return
+		}
+		p.pausedUntil = time.Time{}
+		p.runs = nil
+		metrics.ScraperPaused.WithLabelValues(portal).Set(0)
+		return
+	}
+
+	p.runs = append(p.runs, circuitRun{at: now, failed: failed})
+	var failures int
+	for len(p.runs) > 0 && now.Sub(p.runs[0].at) > c.window {
+		p.runs = p.runs[1:]
+	}
+	for _, r := range p.runs {
+		if r.failed {
+			failures++
+		}
+	}
+	if len(p.runs) >= circuitMinRuns && float64(failures)/float64(len(p.runs)) >= c.threshold {
+		p.pausedUntil = now.Add(c.cooldown)
+		metrics.ScraperPaused.WithLabelValues(portal).Set(1)
+	}
+}
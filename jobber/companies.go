@@ -0,0 +1,49 @@
+package jobber
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompanyAliases maps canonical company names to their variants,
+// ie. "Google": ["Google LLC", "Google Inc."].
+type CompanyAliases map[string][]string
+
+// ParseCompanyAliases reads company aliases in JSON.
+func ParseCompanyAliases(r io.Reader) (CompanyAliases, error) {
+	var a CompanyAliases
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, fmt.Errorf("invalid company aliases: %w", err)
+	}
+	return a, nil
+}
+
+// WithCompanyAliases collapses the scraped company names matching one of the
+// variants into their canonical name before storing the offers. The scraped
+// name is kept as the offer's raw company. Matching ignores case and spacing.
+func WithCompanyAliases(a CompanyAliases) Option {
+	return func(j *Jobber) {
+		j.companies = make(map[string]string)
+		for canonical, variants := range a {
+			j.companies[companyKey(canonical)] = canonical
+			for _, v := range variants {
+				j.companies[companyKey(v)] = canonical
+			}
+		}
+	}
+}
+
+func companyKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// canonicalCompany returns the company's canonical name,
+// or the name itself if it has no aliases.
+func (j *Jobber) canonicalCompany(name string) string {
+	if c, ok := j.companies[companyKey(name)]; ok {
+		return c
+	}
+	return name
+}
@@ -7,10 +7,15 @@ package jobber
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 
 	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/alwedo/jobber/db"
@@ -20,33 +25,223 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/robfig/cron/v3"
 )
 
+const (
+	defaultShutdownTimeout      = 10 * time.Second
+	defaultDrainTimeout         = 10 * time.Second
+	defaultInitialScrapeTimeout = 10 * time.Second
+	defaultMaxConcurrentScrapes = 3
+	defaultIntervalHours        = 1
+	defaultOfferRetention       = 7 * 24 * time.Hour
+	defaultCleanupCron          = "0 2 * * *" // Every day at 2:00 am.
+	defaultQueriedAtThrottle    = 15 * time.Minute
+	minGroupStoreTimeout        = time.Second // Time left to store a group once its initial scrapes are done.
+	cleanupJobName              = "delete old offers"
+	// DefaultOffersLimit is how many offers ListOffers returns when
+	// no limit is passed. Limits are capped to MaxOffersLimit.
+	DefaultOffersLimit = 100
+	MaxOffersLimit     = 500
+)
+
+// ErrInvalidQuery is returned for keywords or locations
+// left empty once canonicalized, like " , ".
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ErrInvalidInterval is returned for query intervals that don't divide
+// a day, as they can't be scheduled evenly with a cron expression.
+var ErrInvalidInterval = errors.New("invalid query interval")
+
+// ErrInvalidTimePostedRange is returned for time posted ranges
+// other than the scrape.TimePosted ones.
+var ErrInvalidTimePostedRange = errors.New("invalid time posted range")
+
+// ErrDebugUnsupported is returned by ScrapeDebug when the
+// scraper doesn't implement scrape.Debugger.
+var ErrDebugUnsupported = errors.New("scraper doesn't support debugging")
+
+// ErrQueryNotFound is returned for the keywords and locations without a query.
+var ErrQueryNotFound = errors.New("query not found")
+
+// ErrQueryExists is returned by CreateQuery when the query already exists,
+// which callers can treat as created.
+var ErrQueryExists = errors.New("query already exists")
+
+// ErrQueryConflict is returned by CreateQuery when a query of the same keywords
+// and location exists with a different remote flag, as they'd share a feed.
+var ErrQueryConflict = errors.New("query exists with other options")
+
+// ErrQueryGroupNotFound is returned for the missing query groups.
+var ErrQueryGroupNotFound = errors.New("query group not found")
+
+// ErrOfferNotFound is returned for the missing offers.
+var ErrOfferNotFound = errors.New("offer not found")
+
 type Jobber struct {
-	ctx    context.Context
-	scpr   scrape.Scraper
-	logger *slog.Logger
-	db     *db.Queries
-	sched  gocron.Scheduler
+	ctx             context.Context
+	scpr            scrape.Scraper
+	logger          *slog.Logger
+	db              Store
+	sched           gocron.Scheduler
+	shutdownTimeout time.Duration
+	drainTimeout    time.Duration
+	// initialScrapeTimeout bounds how long CreateQuery waits for the initial scrape.
+	initialScrapeTimeout time.Duration
+	// storeOrphanOffers stores the offers scraped for a query that was
+	// deleted mid-scrape instead of discarding them. They won't be
+	// associated to any query and will be pruned with the old offers.
+	storeOrphanOffers bool
+	// scrapes bounds the queries running at the same time, so queries
+	// scheduled on the same minute don't hammer the job portal.
+	scrapes              chan struct{}
+	maxConcurrentScrapes int
+	// gathering holds the queries whose initial scrape is still
+	// running, keyed by queryKey.
+	gathering sync.Map
+	// pending holds the IDs of the queries with a run waiting or in progress.
+	// Scrapes retrying with backoff can outlast the query's interval, and the
+	// next run must not scrape the same query concurrently.
+	pending  sync.Map
+	notifier Notifier
+	seen     *seenCache
+	circuit  *circuit
+	// companies maps the company names' variants to their canonical name.
+	companies map[string]string
+	// offerRetention is how long offers are kept since posted,
+	// unless their portal has its own in portalRetention.
+	offerRetention  time.Duration
+	portalRetention map[string]time.Duration
+	cleanupCron     string
+	// queriedAtThrottle is how old a query's queried_at must be for a read to update it.
+	queriedAtThrottle time.Duration
+	// inFlight tracks the running queries so the closer can let them finish
+	// persisting their offers. Once closing is set no new queries start.
+	mu       sync.Mutex
+	closing  bool
+	inFlight sync.WaitGroup
+	// paused stops the scheduler from running any job, see Pause.
+	pauseMu sync.Mutex
+	paused  bool
+}
+
+type Option func(*Jobber)
+
+// WithShutdownTimeout sets how long the closer waits for running
+// jobs to finish before abandoning them. Defaults to 10 seconds.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(j *Jobber) {
+		j.shutdownTimeout = d
+	}
 }
 
-func New(log *slog.Logger, db *db.Queries) (*Jobber, func()) {
-	return NewConfigurableJobber(log, db, scrape.LinkedIn())
+// WithDrainTimeout sets how long the closer waits for running queries
+// to finish storing their offers before canceling them. Defaults to 10 seconds.
+func WithDrainTimeout(d time.Duration) Option {
+	return func(j *Jobber) {
+		j.drainTimeout = d
+	}
+}
+
+// WithInitialScrapeTimeout sets how long CreateQuery waits for the new
+// query's initial scrape to populate its feed. Defaults to 10 seconds.
+func WithInitialScrapeTimeout(d time.Duration) Option {
+	return func(j *Jobber) {
+		j.initialScrapeTimeout = d
+	}
+}
+
+// WithOfferRetention sets how long offers are kept since they were posted.
+// Defaults to 7 days.
+func WithOfferRetention(d time.Duration) Option {
+	return func(j *Jobber) {
+		j.offerRetention = d
+	}
+}
+
+// WithPortalRetention sets how long offers from a portal, identified by its
+// source (ie. scrape.SourceLinkedIn), are kept since they were posted.
+// Portals without their own retention use the WithOfferRetention one.
+func WithPortalRetention(source string, d time.Duration) Option {
+	return func(j *Jobber) {
+		j.portalRetention[source] = d
+	}
+}
+
+// WithCleanupCron sets the cron expression deleting the old offers.
+// Defaults to every day at 2:00 am, which is also used if expr is invalid.
+func WithCleanupCron(expr string) Option {
+	return func(j *Jobber) {
+		j.cleanupCron = expr
+	}
+}
+
+// WithQueriedAtThrottle sets how often the reads of a query update its queried_at,
+// which keeps it from being deleted for being unused, so readers polling it don't
+// write it every time. Defaults to 15 minutes, 0 updates it on every read.
+func WithQueriedAtThrottle(d time.Duration) Option {
+	return func(j *Jobber) {
+		j.queriedAtThrottle = d
+	}
+}
+
+// WithMaxConcurrentScrapes sets how many queries can run at
+// the same time, the rest wait for their turn. Defaults to 3.
+func WithMaxConcurrentScrapes(n int) Option {
+	return func(j *Jobber) {
+		j.maxConcurrentScrapes = n
+	}
+}
+
+// WithStoreOrphanOffers keeps the offers scraped for
+// queries that were deleted while being scraped.
+func WithStoreOrphanOffers() Option {
+	return func(j *Jobber) {
+		j.storeOrphanOffers = true
+	}
 }
 
-func NewConfigurableJobber(log *slog.Logger, db *db.Queries, s scrape.Scraper) (*Jobber, func()) {
-	sched, err := gocron.NewScheduler()
+func New(log *slog.Logger, db Store, opts ...Option) (*Jobber, func()) {
+	s, err := scrape.LinkedIn()
 	if err != nil {
-		log.Error("failed to create scheduler", slog.String("error", err.Error()))
+		log.Error("failed to create LinkedIn scraper", slog.String("error", err.Error()))
 	}
+	return NewConfigurableJobber(log, db, s, opts...)
+}
+
+func NewConfigurableJobber(log *slog.Logger, db Store, s scrape.Scraper, opts ...Option) (*Jobber, func()) {
 	ctx, cancelCtx := context.WithCancel(context.Background())
 	j := &Jobber{
-		ctx:    ctx,
-		scpr:   s,
-		logger: log,
-		db:     db,
-		sched:  sched,
+		ctx:                  ctx,
+		scpr:                 s,
+		logger:               log,
+		db:                   db,
+		shutdownTimeout:      defaultShutdownTimeout,
+		drainTimeout:         defaultDrainTimeout,
+		initialScrapeTimeout: defaultInitialScrapeTimeout,
+		maxConcurrentScrapes: defaultMaxConcurrentScrapes,
+		notifier:             newWebhookNotifier(),
+		seen:                 newSeenCache(defaultSeenCacheSize),
+		circuit:              newCircuit(defaultCircuitThreshold, defaultCircuitWindow, defaultCircuitCooldown),
+		offerRetention:       defaultOfferRetention,
+		portalRetention:      make(map[string]time.Duration),
+		cleanupCron:          defaultCleanupCron,
+		queriedAtThrottle:    defaultQueriedAtThrottle,
+	}
+	for _, o := range opts {
+		o(j)
+	}
+	if _, err := cron.ParseStandard(j.cleanupCron); err != nil {
+		log.Error("invalid cleanup cron, using the default one", slog.String("cron", j.cleanupCron), slog.String("error", err.Error()))
+		j.cleanupCron = defaultCleanupCron
+	}
+	j.scrapes = make(chan struct{}, j.maxConcurrentScrapes)
+
+	sched, err := gocron.NewScheduler(gocron.WithStopTimeout(j.shutdownTimeout))
+	if err != nil {
+		log.Error("failed to create scheduler", slog.String("error", err.Error()))
 	}
+	j.sched = sched
 
 	// Initial job scheduling.
 	queries, err := j.db.ListQueries(j.ctx)
@@ -57,30 +252,101 @@ func NewConfigurableJobber(log *slog.Logger, db *db.Queries, s scrape.Scraper) (
 		j.scheduleQuery(q)
 	}
 	j.schedDeleteOldOffers()
+	j.schedObserveOffersPerQuery()
 	j.sched.Start()
+	j.observeActiveJobs()
 
 	return j, func() {
+		// Running queries get the chance to finish storing their offers.
+		// Canceling the context afterwards makes the ones that didn't
+		// return promptly instead of blocking the scheduler shutdown.
+		if !j.drain() {
+			j.logger.Warn("canceling running queries after drain timeout", slog.Duration("timeout", j.drainTimeout))
+		}
 		cancelCtx()
 		if err := j.sched.Shutdown(); err != nil {
+			if errors.Is(err, gocron.ErrStopJobsTimedOut) {
+				j.logger.Warn("abandoned running jobs after shutdown timeout", slog.Duration("timeout", j.shutdownTimeout))
+				return
+			}
 			j.logger.Error("failed to shutdown scheduler", slog.String("error", err.Error()))
 		}
 	}
 }
 
-// CreateQuery creates a new query and schedules it.
-// If the query already exists the creation will be ignored.
-func (j *Jobber) CreateQuery(keywords, location string) error {
-	query, err := j.db.CreateQuery(j.ctx, &db.CreateQueryParams{
-		Keywords: keywords,
-		Location: location,
+// QueryOptions are the optional settings of the created queries.
+// The zero value creates hourly queries looking for the past week's offers.
+type QueryOptions struct {
+	// IntervalHours is how often the query runs, which must divide a day.
+	// Zero runs it hourly.
+	IntervalHours int
+	// Since is how far back the first scrape looks for offers, one of the
+	// scrape.TimePosted ranges. Empty looks for the past week's offers.
+	Since string
+	// Remote queries only look for remote offers, anywhere when their location is empty.
+	Remote bool
+	// GeoID is LinkedIn's numeric ID of the location, which it otherwise
+	// finds by name, sometimes picking the wrong one.
+	GeoID string
+}
+
+// validate checks the options, filling in the defaults.
+func (o *QueryOptions) validate() error {
+	if strings.Trim(o.GeoID, "0123456789") != "" {
+		return fmt.Errorf("%w: geo id %q isn't numeric", ErrInvalidQuery, o.GeoID)
+	}
+	if o.IntervalHours == 0 {
+		o.IntervalHours = defaultIntervalHours
+	}
+	if o.IntervalHours < 0 || o.IntervalHours > 24 || 24%o.IntervalHours != 0 {
+		return fmt.Errorf("%w: %d hours", ErrInvalidInterval, o.IntervalHours)
+	}
+	if o.Since == "" {
+		o.Since = scrape.TimePostedWeek
+	}
+	if !scrape.ValidTimePostedRange(o.Since) {
+		return fmt.Errorf("%w: %s", ErrInvalidTimePostedRange, o.Since)
+	}
+	return nil
+}
+
+// CreateQuery creates a new query and schedules it to run, see QueryOptions.
+// Keywords and location are canonicalized, so their variants map to the same query,
+// and mustn't be empty once canonicalized, but for the location of remote queries.
+// If the query already exists the creation will be ignored, returning ErrQueryExists,
+// or ErrQueryConflict if the existing one doesn't match opts.Remote.
+// The context only bounds the DB call, not the initial scrape. It returns false
+// if the initial scrape was still running after the initial scrape timeout.
+func (j *Jobber) CreateQuery(ctx context.Context, keywords, location string, opts QueryOptions) (bool, error) {
+	keywords, location = canonicalize(keywords, location)
+	if keywords == "" || (location == "" && !opts.Remote) {
+		return false, fmt.Errorf("%w: keywords and location can't be empty", ErrInvalidQuery)
+	}
+	if err := opts.validate(); err != nil {
+		return false, err
+	}
+	query, err := j.db.CreateQuery(ctx, &db.CreateQueryParams{
+		Keywords:        keywords,
+		Location:        location,
+		IntervalHours:   int32(opts.IntervalHours), //nolint: gosec
+		TimePostedRange: opts.Since,
+		Remote:          opts.Remote,
+		GeoID:           opts.GeoID,
 	})
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+		existing, err := j.db.GetQuery(ctx, &db.GetQueryParams{Keywords: keywords, Location: location})
+		if err != nil {
+			return false, fmt.Errorf("failed to get existing query: %w", err)
+		}
+		if existing.Remote != opts.Remote {
+			return false, fmt.Errorf("%w: %q in %q has remote %t", ErrQueryConflict, keywords, location, existing.Remote)
+		}
 		// If the query exist we just return. The server will respond with the RSS feed url.
-		return nil
+		return true, ErrQueryExists
 	}
 	if err != nil {
-		return fmt.Errorf("failed to create query: %w", err)
+		return false, fmt.Errorf("failed to create query: %w", err)
 	}
 	j.logger.Info("created new query",
 		slog.Int64("queryID", query.ID),
@@ -92,45 +358,466 @@ func (j *Jobber) CreateQuery(keywords, location string) error {
 	// After creating a new query we schedule it and run it immediately
 	// so the feed has initial data. In the frontend we use a spinner
 	// with htmx while this is being processed.
-	done := make(chan struct{})
+	// The listener fires after every run of the job, not just the initial
+	// one, and nobody waits for it once we timed out, so it only closes done.
+	var (
+		done     = make(chan struct{})
+		doneOnce sync.Once
+	)
+	// The query stops gathering once a run is done, whether it stored
+	// offers or returned early.
+	key := queryKey{keywords, location}
 	o := []gocron.JobOption{
 		gocron.WithStartAt(gocron.WithStartImmediately()),
 		gocron.WithEventListeners(gocron.AfterJobRuns(func(uuid.UUID, string) {
-			done <- struct{}{}
-			defer close(done)
+			j.gathering.Delete(key)
+			doneOnce.Do(func() { close(done) })
 		})),
 	}
 
+	// While paused the query is only scheduled, it runs once resumed.
+	j.pauseMu.Lock()
+	paused := j.paused
+	j.pauseMu.Unlock()
+	if paused {
+		j.scheduleQuery(query)
+		j.logger.Info("scheduling paused, skipping initial scrape in jobber.CreateQuery", slog.Int64("queryID", query.ID))
+		return false, nil
+	}
+
+	j.gathering.Store(key, struct{}{})
 	j.scheduleQuery(query, o...)
 
 	// Blocks and waits for the job to finish or for a timeout.
 	select {
 	case <-done:
-	case <-time.After(10 * time.Second):
-		j.logger.Info("scheduleQuery in jobber.CreateQuery took more than 10 sec", slog.String("keywords", keywords), slog.String("location", location))
+		return true, nil
+	case <-time.After(j.initialScrapeTimeout):
+		j.logger.Info("initial scrape in jobber.CreateQuery timed out", slog.Duration("timeout", j.initialScrapeTimeout), slog.String("keywords", keywords), slog.String("location", location))
+		return false, nil
 	}
+}
 
-	return nil
+// GroupQuery is one of the keywords and location pairs of a query group.
+type GroupQuery struct {
+	Keywords string
+	Location string
+}
+
+// CreateQueryGroup creates the queries of a group, like CreateQuery, and the
+// group aggregating them, returning its ID. Their variants map to the same
+// queries, and creating the same set of queries again returns the existing group.
+// The queries are created concurrently, so it returns false if any of their
+// initial scrapes was still running after the initial scrape timeout.
+// The options apply to all the queries, so a geo ID needs them to share
+// their location. Like for CreateQuery, the context only bounds the DB
+// calls: the group is stored once the initial scrapes are done, with as
+// much time as it has left, or minGroupStoreTimeout if it expired.
+func (j *Jobber) CreateQueryGroup(ctx context.Context, queries []GroupQuery, opts QueryOptions) (int64, bool, error) {
+	if len(queries) == 0 {
+		return 0, false, fmt.Errorf("%w: a group needs at least a query", ErrInvalidQuery)
+	}
+	canonical := make([]GroupQuery, len(queries))
+	for i, q := range queries {
+		k, l := canonicalize(q.Keywords, q.Location)
+		if k == "" || (l == "" && !opts.Remote) {
+			return 0, false, fmt.Errorf("%w: keywords and location can't be empty", ErrInvalidQuery)
+		}
+		if i > 0 && opts.GeoID != "" && l != canonical[0].Location {
+			return 0, false, fmt.Errorf("%w: a geo id can't locate %q and %q", ErrInvalidQuery, canonical[0].Location, l)
+		}
+		canonical[i] = GroupQuery{Keywords: k, Location: l}
+	}
+	deadline, hasDeadline := ctx.Deadline()
+
+	var (
+		wg    sync.WaitGroup
+		dones = make([]bool, len(canonical))
+		errs  = make([]error, len(canonical))
+	)
+	for i, q := range canonical {
+		wg.Go(func() {
+			dones[i], errs[i] = j.CreateQuery(ctx, q.Keywords, q.Location, opts)
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, ErrQueryExists) {
+			return 0, false, err
+		}
+	}
+	// The initial scrapes can outlast the context. The member queries exist
+	// by now, so the group is stored even if the caller is gone, otherwise
+	// they'd be left without it, with the time the context has left but at
+	// least minGroupStoreTimeout.
+	ctx = context.WithoutCancel(ctx)
+	if hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, max(time.Until(deadline), minGroupStoreTimeout))
+		defer cancel()
+	}
+
+	var ids []int64
+	for _, q := range canonical {
+		query, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+			Keywords: q.Keywords,
+			Location: q.Location,
+		})
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+		}
+		ids = append(ids, query.ID)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	memberIDs := make([]string, len(ids))
+	for i, id := range ids {
+		memberIDs[i] = strconv.FormatInt(id, 10)
+	}
+	group, err := j.db.CreateQueryGroup(ctx, strings.Join(memberIDs, ","))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create query group: %w", err)
+	}
+	for _, id := range ids {
+		if err := j.db.CreateQueryGroupMember(ctx, &db.CreateQueryGroupMemberParams{GroupID: group.ID, QueryID: id}); err != nil {
+			return 0, false, fmt.Errorf("failed to add query group member: %w", err)
+		}
+	}
+	j.logger.Info("created query group", slog.Int64("groupID", group.ID), slog.String("queryIDs", group.MemberIds))
+	return group.ID, !slices.Contains(dones, false), nil
 }
 
 // ListOffers return the list of offers posted in the last 7 days for a
-// given query's keywords and location.
-// If the query doesn't exist, a sql.ErrNoRows will be returned.
-func (j *Jobber) ListOffers(keywords, location string) ([]*db.Offer, error) {
-	q, err := j.db.GetQuery(j.ctx, &db.GetQueryParams{
+// given query's keywords and location, most recent first, paginated by
+// limit and offset. A zero limit returns up to DefaultOffersLimit offers.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) ListOffers(ctx context.Context, keywords, location string, limit, offset int) ([]*db.Offer, error) {
+	keywords, location = canonicalize(keywords, location)
+	if limit <= 0 {
+		limit = DefaultOffersLimit
+	}
+	limit = min(limit, MaxOffersLimit)
+	offset = max(offset, 0)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
 		Keywords: keywords,
 		Location: location,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get query: %w", err)
+		return nil, fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
 	}
-	if err := j.db.UpdateQueryQAT(j.ctx, q.ID); err != nil {
+	if err := j.touchQuery(ctx, q.ID); err != nil {
 		j.logger.Error("unable to update query timestamp", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
 	}
-	return j.db.ListOffers(j.ctx, q.ID)
+	return j.db.ListOffers(ctx, &db.ListOffersParams{
+		ID:     q.ID,
+		Limit:  int32(limit),                //nolint: gosec
+		Offset: int32(min(offset, 1<<31-1)), //nolint: gosec
+	})
+}
+
+// MergeOffers merges the offers listed for several queries, the most recently
+// posted first. The offers associated with more than one of the queries are
+// only kept once, as identified by their source and ID.
+func MergeOffers(lists ...[]*db.Offer) []*db.Offer {
+	type offerKey struct{ source, id string }
+	var (
+		offers []*db.Offer
+		seen   = make(map[offerKey]struct{})
+	)
+	for _, l := range lists {
+		for _, o := range l {
+			k := offerKey{source: o.Source, id: o.ID}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			offers = append(offers, o)
+		}
+	}
+	slices.SortStableFunc(offers, func(a, b *db.Offer) int {
+		return b.PostedAt.Time.Compare(a.PostedAt.Time)
+	})
+	return offers
+}
+
+// ListQueryGroup returns the queries of a group. If the group doesn't
+// exist, or lost its queries, ErrQueryGroupNotFound will be returned.
+func (j *Jobber) ListQueryGroup(ctx context.Context, id int64) ([]*db.Query, error) {
+	queries, err := j.db.ListQueryGroupMembers(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list query group members: %w", err)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("failed to list query group members: %w", ErrQueryGroupNotFound)
+	}
+	return queries, nil
+}
+
+// TouchQuery marks the query as read, like ListOffers, for the
+// reads served without listing its offers, ie. from a cache.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) TouchQuery(ctx context.Context, keywords, location string) error {
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	if err := j.touchQuery(ctx, q.ID); err != nil {
+		return fmt.Errorf("failed to update query timestamp: %w", err)
+	}
+	return nil
+}
+
+// touchQuery updates the query's queried_at, if it's older than the throttle.
+func (j *Jobber) touchQuery(ctx context.Context, id int64) error {
+	_, err := j.db.UpdateQueryQATIfStale(ctx, &db.UpdateQueryQATIfStaleParams{ID: id, StaleAfter: j.queriedAtThrottle.Seconds()})
+	return err
+}
+
+// SearchOffers returns up to limit stored offers, of any query, matching all
+// the words of the search in their title, company or location, the most
+// relevant first. A zero limit returns up to DefaultOffersLimit offers.
+func (j *Jobber) SearchOffers(ctx context.Context, search string, limit int) ([]*db.Offer, error) {
+	if limit <= 0 {
+		limit = DefaultOffersLimit
+	}
+	offers, err := j.db.SearchOffers(ctx, &db.SearchOffersParams{
+		Query: search,
+		Limit: int32(min(limit, MaxOffersLimit)), //nolint: gosec
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search offers: %w", err)
+	}
+	return offers, nil
+}
+
+// CountOffers returns the amount of offers of a query. Unlike ListOffers
+// it doesn't count as the query being read, so badges polling it don't
+// keep otherwise unused queries alive.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) CountOffers(ctx context.Context, keywords, location string) (int64, error) {
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	return j.db.CountOffers(ctx, q.ID)
+}
+
+// CountOffersByCompany returns how many offers of a query each company has,
+// the companies with more offers first. Like CountOffers, it doesn't count
+// as the query being read.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) CountOffersByCompany(ctx context.Context, keywords, location string) ([]*db.CountOffersByCompanyRow, error) {
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	return j.db.CountOffersByCompany(ctx, q.ID)
+}
+
+// LastError returns when the query's last run failed, or the zero time
+// if it succeeded. Like CountOffers, it doesn't count as the query being read.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) LastError(ctx context.Context, keywords, location string) (time.Time, error) {
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	if q.LastError == "" {
+		return time.Time{}, nil
+	}
+	return q.LastErrorAt.Time, nil
+}
+
+// GetQuery returns the query of the keywords and location.
+func (j *Jobber) GetQuery(ctx context.Context, keywords, location string) (*db.Query, error) {
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	return q, nil
+}
+
+// ListPopularQueries returns up to limit queries in use, the most recently read first.
+func (j *Jobber) ListPopularQueries(ctx context.Context, limit int) ([]*db.Query, error) {
+	return j.db.ListPopularQueries(ctx, int32(min(limit, 1<<31-1))) //nolint: gosec
+}
+
+// Gathering reports whether a newly created query is
+// still running its initial scrape and has no data yet.
+func (j *Jobber) Gathering(keywords, location string) bool {
+	keywords, location = canonicalize(keywords, location)
+	_, ok := j.gathering.Load(queryKey{keywords, location})
+	return ok
+}
+
+// DeleteQuery deletes a query with its offer associations and unschedules it.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) DeleteQuery(ctx context.Context, keywords, location string) error {
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	if err := j.deleteQuery(ctx, q); err != nil {
+		return fmt.Errorf("failed to delete query: %w", err)
+	}
+	j.logger.Info("deleted query", slog.Int64("queryID", q.ID), slog.String("keywords", q.Keywords), slog.String("location", q.Location))
+	return nil
+}
+
+// Pause stops running the scheduled jobs, ie. while the job portal blocks us,
+// without losing the queries. It waits for the running jobs to finish, up to
+// the shutdown timeout. Queries created while paused skip their initial scrape.
+func (j *Jobber) Pause() error {
+	j.pauseMu.Lock()
+	defer j.pauseMu.Unlock()
+	if j.paused {
+		return nil
+	}
+	if err := j.sched.StopJobs(); err != nil {
+		if !errors.Is(err, gocron.ErrStopJobsTimedOut) {
+			return fmt.Errorf("failed to stop scheduler: %w", err)
+		}
+		// The scheduler is stopped, the running jobs just keep going.
+		j.logger.Warn("jobs still running after pausing scheduling", slog.Duration("timeout", j.shutdownTimeout))
+	}
+	j.paused = true
+	j.logger.Info("paused scheduling")
+	return nil
+}
+
+// Resume starts running the scheduled jobs again after a Pause.
+func (j *Jobber) Resume() {
+	j.pauseMu.Lock()
+	defer j.pauseMu.Unlock()
+	if !j.paused {
+		return
+	}
+	j.sched.Start()
+	j.paused = false
+	j.logger.Info("resumed scheduling")
+}
+
+// Paused reports whether the scheduling is paused.
+func (j *Jobber) Paused() bool {
+	j.pauseMu.Lock()
+	defer j.pauseMu.Unlock()
+	return j.paused
+}
+
+// RunNow runs the query right away, on top of its scheduled runs,
+// or once resumed when the scheduling is paused.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) RunNow(ctx context.Context, keywords, location string) error {
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	if _, err := j.sched.NewJob(
+		gocron.OneTimeJob(gocron.OneTimeJobStartImmediately()),
+		gocron.NewTask(func(q int64) { j.runQuery(q) }, q.ID),
+		// Tagged like the scheduled job, so deleting the query removes it too.
+		gocron.WithTags(queryKey{q.Keywords, q.Location}.tag()),
+	); err != nil {
+		return fmt.Errorf("failed to schedule query run: %w", err)
+	}
+	j.observeActiveJobs()
+	j.logger.Info("scheduled query run", slog.Int64("queryID", q.ID), slog.String("keywords", q.Keywords), slog.String("location", q.Location))
+	return nil
+}
+
+// ScrapeDebug scrapes the keywords and location without storing anything,
+// returning the scraper's debug info, ie. to tell which selector broke when
+// the scrapes find fewer offers. The query doesn't need to exist.
+// The debug info of a failed scrape is returned along with the error.
+func (j *Jobber) ScrapeDebug(ctx context.Context, keywords, location string, remote bool) (*scrape.DebugInfo, error) {
+	keywords, location = canonicalize(keywords, location)
+	if keywords == "" || (location == "" && !remote) {
+		return nil, fmt.Errorf("%w: keywords and location can't be empty", ErrInvalidQuery)
+	}
+	d, ok := j.scpr.(scrape.Debugger)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrDebugUnsupported, j.scpr.Name())
+	}
+	_, info, err := d.ScrapeDebug(ctx, &db.Query{
+		Keywords:        keywords,
+		Location:        location,
+		TimePostedRange: scrape.TimePostedWeek,
+		Remote:          remote,
+	})
+	if err != nil {
+		return info, fmt.Errorf("failed to scrape: %w", err)
+	}
+	return info, nil
+}
+
+// GetOffer returns a single offer by its source and ID.
+// If the offer doesn't exist, ErrOfferNotFound will be returned.
+func (j *Jobber) GetOffer(ctx context.Context, source, id string) (*db.Offer, error) {
+	o, err := j.db.GetOffer(ctx, &db.GetOfferParams{Source: source, ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get offer: %w", notFound(err, ErrOfferNotFound))
+	}
+	return o, nil
 }
 
 func (j *Jobber) runQuery(qID int64) {
+	j.mu.Lock()
+	if j.closing {
+		j.mu.Unlock()
+		return
+	}
+	j.inFlight.Add(1)
+	j.mu.Unlock()
+	defer j.inFlight.Done()
+
+	if _, ok := j.pending.LoadOrStore(qID, struct{}{}); ok {
+		j.logger.Info("previous run still pending, skipping jobber.runQuery", slog.Int64("queryID", qID))
+		return
+	}
+	metrics.JobberPendingRuns.Inc()
+	defer func() {
+		j.pending.Delete(qID)
+		metrics.JobberPendingRuns.Dec()
+	}()
+
+	metrics.JobberQueuedRuns.Inc()
+	select {
+	case j.scrapes <- struct{}{}:
+		metrics.JobberQueuedRuns.Dec()
+		defer func() { <-j.scrapes }()
+	case <-j.ctx.Done():
+		metrics.JobberQueuedRuns.Dec()
+		return
+	}
+
 	q, err := j.db.GetQueryByID(j.ctx, qID)
 	if err != nil {
 		j.logger.Error("unable to get query in jobber.runQuery", slog.Int64("queryID", qID), slog.String("error", err.Error()))
@@ -139,38 +826,150 @@ func (j *Jobber) runQuery(qID int64) {
 
 	// We remove queries that haven't been used for longer than 7 days.
 	if time.Since(q.QueriedAt.Time) > time.Hour*24*7 {
-		if err := j.db.DeleteQuery(j.ctx, q.ID); err != nil {
+		if err := j.deleteQuery(j.ctx, q); err != nil {
 			j.logger.Error("unable to delete query in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
+			return
 		}
-		j.sched.RemoveByTags(q.Keywords + q.Location)
-		metrics.JobberScheduledQueries.WithLabelValues(fmt.Sprintf("%d", q.ID), q.Keywords+q.Location, "").Dec()
-
-		j.logger.Info("deleting unused query", slog.Int64("queryID", q.ID), slog.String("keywords", q.Keywords), slog.String("location", q.Location))
+		metrics.JobberExpiredQueries.WithLabelValues(q.Keywords, q.Location).Inc()
+		j.logger.Info("deleted unused query", slog.Int64("queryID", q.ID), slog.String("keywords", q.Keywords), slog.String("location", q.Location), slog.Time("queriedAt", q.QueriedAt.Time))
 		return
 	}
 
+	portal := j.scpr.Name()
+	if !j.circuit.allow(portal) {
+		j.logger.Info("scraping paused, skipping jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("portal", portal))
+		return
+	}
+	start := time.Now()
 	offers, err := j.scpr.Scrape(j.ctx, q)
+	elapsed := time.Since(start)
+	found := len(offers)
+	// Scrapes canceled by a shutdown don't tell anything about the portal.
+	if j.ctx.Err() == nil {
+		j.circuit.record(portal, err != nil)
+	}
 	if err != nil {
+		var sErr *scrape.Error
+		if errors.As(err, &sErr) {
+			metrics.ScraperErrors.WithLabelValues(portal, sErr.Reason).Inc()
+		}
 		if errors.Is(err, scrape.ErrRetryable) {
 			// Retryable errors still bring data. We log a warning for further analysis and continue.
-			j.logger.Warn("exhausted retries in jobber.runQuery", slog.Int64("queryID", q.ID), slog.Any("error", err))
+			j.logger.Warn("exhausted retries in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("portal", portal), slog.Any("error", err))
 		} else {
-			j.logger.Error("scrape in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
+			j.logger.Error("scrape in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("portal", portal), slog.String("error", err.Error()))
+			// We keep the error so the query's feed can tell it's stale.
+			if err := j.db.UpdateQueryError(j.ctx, &db.UpdateQueryErrorParams{ID: q.ID, LastError: err.Error()}); err != nil {
+				j.logger.Error("unable to update query error in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
+			}
 			return
 		}
 	}
-	if len(offers) > 0 {
-		for _, o := range offers {
-			if err := j.db.CreateOffer(j.ctx, &o); err != nil {
-				j.logger.Error("unable to create offer in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
+	metrics.ScraperJob.WithLabelValues(
+		portal,
+		q.Keywords,
+		q.Location,
+		strconv.Itoa(found),
+	).Observe(elapsed.Seconds())
+	for i := range offers {
+		offers[i].RawCompany = offers[i].Company
+		offers[i].Company = j.canonicalCompany(offers[i].Company)
+	}
+	// Offers associated by the previous runs don't need to be associated nor
+	// notified again, and only need to be upserted if their posting changed.
+	var (
+		seen   = make(map[offerKey]bool, len(offers))
+		hashes = make(map[offerKey]uint64, len(offers))
+		fresh  []db.CreateOfferParams
+		upsert []db.CreateOfferParams
+	)
+	for _, o := range offers {
+		k := offerKey{o.Source, o.ID}
+		hashes[k] = offerHash(&o)
+		if h, ok := j.seen.get(seenKey{q.ID, k}); ok {
+			seen[k] = true
+			if h != hashes[k] {
+				upsert = append(upsert, o)
+			}
+			continue
+		}
+		fresh = append(fresh, o)
+		upsert = append(upsert, o)
+	}
+
+	// The query might have been deleted while scraping, ie. by DeleteQuery.
+	alive := true
+	if _, err := j.db.GetQueryByID(j.ctx, q.ID); errors.Is(err, sql.ErrNoRows) {
+		alive = false
+		if !j.storeOrphanOffers {
+			j.logger.Info("query deleted while scraping, skipping offers in jobber.runQuery", slog.Int64("queryID", q.ID))
+			return
+		}
+	}
+	// We find the offers to notify before storing them, to know which ones are new.
+	var (
+		notification *db.QueryNotification
+		notify       []db.CreateOfferParams
+	)
+	if alive && len(fresh) > 0 {
+		notification, notify, err = j.offersToNotify(q, fresh)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			j.logger.Error("unable to find offers to notify in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
+		}
+	}
+	var created int
+	if len(upsert) > 0 {
+		for _, o := range upsert {
+			// Offers stored by other queries are updated, as their posting may have changed.
+			p := db.UpsertOfferParams(o)
+			inserted, err := j.db.UpsertOffer(j.ctx, &p)
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+				// db.Queries upserts with ON CONFLICT DO UPDATE, so it never returns this: two queries
+				// storing the same offer concurrently both succeed and only one of them inserts it.
+				// It's kept for the stores inserting plainly, where the offer just needs the association.
+				j.logger.Debug("offer stored concurrently in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("offerID", o.ID))
+				err = nil
+			}
+			if err != nil {
+				j.logger.Error("unable to upsert offer in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
+				continue
+			}
+			if inserted {
+				created++
+			}
+			if seen[offerKey{o.Source, o.ID}] {
+				j.seen.add(seenKey{q.ID, offerKey{o.Source, o.ID}}, hashes[offerKey{o.Source, o.ID}])
+				continue
+			}
+			if !alive {
 				continue
 			}
 			if err := j.db.CreateQueryOfferAssoc(j.ctx, &db.CreateQueryOfferAssocParams{
-				QueryID: q.ID,
-				OfferID: o.ID,
+				QueryID:     q.ID,
+				OfferSource: o.Source,
+				OfferID:     o.ID,
 			}); err != nil {
+				if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+					// The query was deleted after we checked it was still there.
+					j.logger.Info("query deleted while storing offers, skipping associations in jobber.runQuery", slog.Int64("queryID", q.ID))
+					alive = false
+					continue
+				}
 				j.logger.Error("unable to create query offer association in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
+				continue
 			}
+			j.seen.add(seenKey{q.ID, offerKey{o.Source, o.ID}}, hashes[offerKey{o.Source, o.ID}])
+		}
+	}
+	metrics.JobberNewOffers.WithLabelValues(q.Keywords, q.Location).Add(float64(created))
+	if !alive {
+		return
+	}
+
+	if len(notify) > 0 {
+		if err := j.notifier.Notify(j.ctx, notification.WebhookUrl, q, notify); err != nil {
+			j.logger.Error("unable to notify new offers in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
 		}
 	}
 
@@ -178,14 +977,62 @@ func (j *Jobber) runQuery(qID int64) {
 		j.logger.Error("unable to update query timestamp in jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("error", err.Error()))
 	}
 
-	j.logger.Debug("successfuly completed jobber.runQuery", slog.Int64("queryID", q.ID), slog.String("keywords", q.Keywords), slog.String("location", q.Location))
+	// Runs finding offers are worth seeing in production, empty ones aren't.
+	level := slog.LevelDebug
+	if found > 0 {
+		level = slog.LevelInfo
+	}
+	j.logger.Log(j.ctx, level, "successfuly completed jobber.runQuery",
+		slog.Int64("queryID", q.ID),
+		slog.String("portal", portal),
+		slog.String("keywords", q.Keywords),
+		slog.String("location", q.Location),
+		slog.Int("offers", found),
+		slog.Int("created", created),
+		slog.Duration("elapsed", elapsed),
+	)
+}
+
+// drain stops new queries from running and waits for the running ones
+// to finish. It returns false if they didn't finish within drainTimeout.
+func (j *Jobber) drain() bool {
+	j.mu.Lock()
+	j.closing = true
+	j.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		j.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(j.drainTimeout):
+		return false
+	}
+}
+
+// deleteQuery removes the query from the DB, which cascades to its offer
+// associations, along with its groups, and removes its scheduled job.
+func (j *Jobber) deleteQuery(ctx context.Context, q *db.Query) error {
+	if err := j.db.DeleteQueryGroupsByQuery(ctx, q.ID); err != nil {
+		return err
+	}
+	if err := j.db.DeleteQuery(ctx, q.ID); err != nil {
+		return err
+	}
+	j.sched.RemoveByTags(queryKey{q.Keywords, q.Location}.tag())
+	j.observeActiveJobs()
+	metrics.JobberScheduledQueries.WithLabelValues(fmt.Sprintf("%d", q.ID), queryKey{q.Keywords, q.Location}.tag(), queryCron(q)).Dec()
+	return nil
 }
 
 func (j *Jobber) scheduleQuery(q *db.Query, o ...gocron.JobOption) {
-	opts := []gocron.JobOption{gocron.WithTags(q.Keywords + q.Location)}
+	opts := []gocron.JobOption{gocron.WithTags(queryKey{q.Keywords, q.Location}.tag())}
 	opts = append(opts, o...)
 
-	cron := fmt.Sprintf("%d * * * *", q.CreatedAt.Time.Minute())
+	cron := queryCron(q)
 	job, err := j.sched.NewJob(
 		gocron.CronJob(cron, false),
 		gocron.NewTask(func(q int64) { j.runQuery(q) }, q.ID),
@@ -196,22 +1043,112 @@ func (j *Jobber) scheduleQuery(q *db.Query, o ...gocron.JobOption) {
 		return
 	}
 
-	metrics.JobberScheduledQueries.WithLabelValues(fmt.Sprintf("%d", q.ID), q.Keywords+q.Location, cron).Inc()
+	metrics.JobberScheduledQueries.WithLabelValues(fmt.Sprintf("%d", q.ID), queryKey{q.Keywords, q.Location}.tag(), cron).Inc()
+	j.observeActiveJobs()
 	j.logger.Info("scheduled query", slog.Int64("queryID", q.ID), slog.String("cron", cron), slog.Any("tags", job.Tags()))
 }
 
+// canonicalTrim are the separators stripped around keywords and locations.
+// Dots and symbols are kept, as in ".net", "c#" or "c++".
+const canonicalTrim = ",;:!?'\"`"
+
+// notFound maps the DB's missing rows to the sentinel error, so
+// callers don't depend on database/sql.
+func notFound(err, sentinel error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return sentinel
+	}
+	return err
+}
+
+// queryKey identifies a query by its canonical keywords and location.
+type queryKey struct {
+	keywords, location string
+}
+
+// tag returns the key as a scheduled job's tag, quoting
+// the keywords and location so they can't run into each other.
+func (k queryKey) tag() string {
+	return strconv.Quote(k.keywords) + " " + strconv.Quote(k.location)
+}
+
+// canonicalize normalizes a query's keywords and location, so variants like
+// " Golang ," and "golang" are the same query: they're lowercased, their
+// surrounding whitespace and separators stripped and their inner whitespace
+// collapsed to single spaces.
+func canonicalize(keywords, location string) (string, string) {
+	c := func(s string) string {
+		s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+		return strings.TrimSpace(strings.Trim(s, canonicalTrim))
+	}
+	return c(keywords), c(location)
+}
+
+// queryCron returns the cron expression running the query every
+// IntervalHours, on the minute of the hour it was created.
+func queryCron(q *db.Query) string {
+	m := q.CreatedAt.Time.Minute()
+	if q.IntervalHours <= 1 {
+		return fmt.Sprintf("%d * * * *", m)
+	}
+	return fmt.Sprintf("%d */%d * * *", m, q.IntervalHours)
+}
+
 func (j *Jobber) schedDeleteOldOffers() {
-	at := "0 2 * * *" // Every day at 2:00 am.
 	_, err := j.sched.NewJob(
-		gocron.CronJob(at, false),
+		gocron.CronJob(j.cleanupCron, false),
 		gocron.NewTask(func() {
-			if err := j.db.DeleteOldOffers(j.ctx); err != nil {
+			if err := j.deleteOldOffers(); err != nil {
 				j.logger.Error("unable to delete old offers", slog.String("error", err.Error()))
 			}
 		}),
 		gocron.WithStartAt(gocron.WithStartImmediately()),
+		gocron.WithName(cleanupJobName),
 	)
 	if err != nil {
 		j.logger.Error("unable to schedule DeleteOldOffers job", slog.String("error", err.Error()))
 	}
 }
+
+// deleteOldOffers deletes the offers posted longer ago than their portal's retention.
+func (j *Jobber) deleteOldOffers() error {
+	p := &db.DeleteOldOffersParams{DefaultRetention: j.offerRetention.Seconds()}
+	for source, d := range j.portalRetention {
+		p.Sources = append(p.Sources, source)
+		p.Retentions = append(p.Retentions, d.Seconds())
+	}
+	return j.db.DeleteOldOffers(j.ctx, p)
+}
+
+func (j *Jobber) schedObserveOffersPerQuery() {
+	at := "30 * * * *" // Every hour, between the queries' scrapes.
+	_, err := j.sched.NewJob(
+		gocron.CronJob(at, false),
+		gocron.NewTask(func() {
+			j.observeOffersPerQuery()
+			// The run now jobs are only removed once they ran.
+			j.observeActiveJobs()
+		}),
+	)
+	if err != nil {
+		j.logger.Error("unable to schedule ObserveOffersPerQuery job", slog.String("error", err.Error()))
+	}
+}
+
+// observeActiveJobs sets the active jobs gauge to the amount of jobs in the scheduler.
+func (j *Jobber) observeActiveJobs() {
+	metrics.JobberActiveJobs.Set(float64(len(j.sched.Jobs())))
+}
+
+// observeOffersPerQuery records the amount of offers associated to each
+// query, so we can see the distribution of feed sizes.
+func (j *Jobber) observeOffersPerQuery() {
+	counts, err := j.db.CountOffersPerQuery(j.ctx)
+	if err != nil {
+		j.logger.Error("unable to count offers per query", slog.String("error", err.Error()))
+		return
+	}
+	for _, c := range counts {
+		metrics.JobberOffersPerQuery.Observe(float64(c))
+	}
+}
@@ -1,17 +1,31 @@
 package jobber
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/synctest"
 	"time"
 
 	"github.com/alwedo/jobber/db"
+	"github.com/alwedo/jobber/metrics"
 	"github.com/alwedo/jobber/scrape"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestConstructor(t *testing.T) {
@@ -25,7 +39,7 @@ func TestConstructor(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	t.Run("constructor schedules existing queries", func(t *testing.T) {
-		wantJobs := 5 // Four queries from DB seed + old offers deletetion.
+		wantJobs := 6 // Four queries from DB seed + old offers deletetion + offers per query.
 		gotJobs := len(j.sched.Jobs())
 
 		if wantJobs != gotJobs {
@@ -34,7 +48,7 @@ func TestConstructor(t *testing.T) {
 	})
 
 	t.Run("old offers should've been deleted", func(t *testing.T) {
-		offers, err := d.ListOffers(context.Background(), 1)
+		offers, err := d.ListOffers(context.Background(), &db.ListOffersParams{ID: 1})
 		if err != nil {
 			t.Errorf("wanted no error, got: %v", err)
 		}
@@ -54,9 +68,13 @@ func TestCreateQuery(t *testing.T) {
 	t.Run("creates a query", func(t *testing.T) {
 		k := "cuak"
 		l := "squeek"
-		if err := j.CreateQuery(k, l); err != nil {
+		done, err := j.CreateQuery(context.Background(), k, l, QueryOptions{})
+		if err != nil {
 			t.Fatalf("failed to create query: %s", err)
 		}
+		if !done {
+			t.Errorf("expected the initial scrape to complete")
+		}
 		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: k, Location: l})
 		if err != nil {
 			t.Errorf("failed to get query: %s", err)
@@ -74,7 +92,7 @@ func TestCreateQuery(t *testing.T) {
 		}
 		time.Sleep(50 * time.Millisecond)
 		for _, jb := range j.sched.Jobs() {
-			if slices.Contains(jb.Tags(), k+l) {
+			if slices.Contains(jb.Tags(), queryKey{k, l}.tag()) {
 				lr, _ := jb.LastRun() //nolint: errcheck
 				if lr.Before(time.Now().Add(-time.Second)) {
 					t.Errorf("expected created query to have been performed immediately, got %v", lr)
@@ -83,9 +101,45 @@ func TestCreateQuery(t *testing.T) {
 		}
 	})
 
+	t.Run("stores the geo id", func(t *testing.T) {
+		if _, err := j.CreateQuery(context.Background(), "golang", "frankfurt", QueryOptions{GeoID: "106772406"}); err != nil {
+			t.Fatalf("failed to create query: %s", err)
+		}
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "frankfurt"})
+		if err != nil {
+			t.Fatalf("failed to get query: %s", err)
+		}
+		if q.GeoID != "106772406" {
+			t.Errorf("expected geo id 106772406, got %q", q.GeoID)
+		}
+		if err := j.DeleteQuery(context.Background(), "golang", "frankfurt"); err != nil {
+			t.Fatalf("failed to delete query: %s", err)
+		}
+	})
+
+	t.Run("non numeric geo id is invalid", func(t *testing.T) {
+		if _, err := j.CreateQuery(context.Background(), "golang", "frankfurt", QueryOptions{GeoID: "frankfurt"}); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery, got %v", err)
+		}
+	})
+
+	t.Run("existing query with another remote flag conflicts", func(t *testing.T) {
+		done, err := j.CreateQuery(context.Background(), "golang", "berlin", QueryOptions{Remote: true})
+		if !errors.Is(err, ErrQueryConflict) {
+			t.Fatalf("expected ErrQueryConflict, got %v", err)
+		}
+		if done {
+			t.Errorf("expected the conflicting query not to be done")
+		}
+	})
+
 	t.Run("on existing query it returns the existing one", func(t *testing.T) {
-		if err := j.CreateQuery("golang", "berlin"); err != nil {
-			t.Fatalf("failed to create existing query: %s", err)
+		done, err := j.CreateQuery(context.Background(), "golang", "berlin", QueryOptions{})
+		if !errors.Is(err, ErrQueryExists) {
+			t.Fatalf("expected ErrQueryExists, got %v", err)
+		}
+		if !done {
+			t.Errorf("expected the existing query to be done")
 		}
 		q, err := d.ListQueries(context.Background())
 		if err != nil {
@@ -102,6 +156,374 @@ func TestCreateQuery(t *testing.T) {
 	})
 }
 
+func TestCreateQueryGroup(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	ctx := context.Background()
+
+	// golang in berlin is seeded, golang in remote is new.
+	queries := []GroupQuery{{Keywords: "Golang", Location: "Berlin"}, {Keywords: "golang", Location: "remote"}}
+	id, done, err := j.CreateQueryGroup(ctx, queries, QueryOptions{})
+	if err != nil {
+		t.Fatalf("failed to create query group: %s", err)
+	}
+	if !done {
+		t.Errorf("expected the initial scrapes to complete")
+	}
+	members, err := j.ListQueryGroup(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to list query group: %s", err)
+	}
+	var got []string
+	for _, m := range members {
+		got = append(got, m.Keywords+" "+m.Location)
+	}
+	if want := []string{"golang berlin", "golang remote"}; !slices.Equal(got, want) {
+		t.Errorf("expected members %v, got %v", want, got)
+	}
+	if !slices.ContainsFunc(j.sched.Jobs(), func(jb gocron.Job) bool { return slices.Contains(jb.Tags(), queryKey{"golang", "remote"}.tag()) }) {
+		t.Errorf("expected the new member query to be scheduled")
+	}
+
+	t.Run("the same queries return the existing group", func(t *testing.T) {
+		again, _, err := j.CreateQueryGroup(ctx, []GroupQuery{queries[1], queries[0], queries[0]}, QueryOptions{})
+		if err != nil {
+			t.Fatalf("failed to create query group: %s", err)
+		}
+		if again != id {
+			t.Errorf("expected the existing group %d, got %d", id, again)
+		}
+	})
+
+	t.Run("invalid queries create nothing", func(t *testing.T) {
+		_, _, err := j.CreateQueryGroup(ctx, []GroupQuery{{Keywords: "rust", Location: "paris"}, {Keywords: "rust"}}, QueryOptions{})
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery, got %v", err)
+		}
+		if _, err := d.GetQuery(ctx, &db.GetQueryParams{Keywords: "rust", Location: "paris"}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected the valid query not to be created, got %v", err)
+		}
+	})
+
+	t.Run("geo ids need a shared location", func(t *testing.T) {
+		_, _, err := j.CreateQueryGroup(ctx, []GroupQuery{{Keywords: "rust", Location: "paris"}, {Keywords: "rust", Location: "lyon"}}, QueryOptions{GeoID: "105015875"})
+		if !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery, got %v", err)
+		}
+	})
+
+	t.Run("options apply to all the queries", func(t *testing.T) {
+		if _, _, err := j.CreateQueryGroup(ctx, []GroupQuery{{Keywords: "rust", Location: "paris"}, {Keywords: "go", Location: "paris"}}, QueryOptions{IntervalHours: 6, GeoID: "105015875"}); err != nil {
+			t.Fatalf("failed to create query group: %v", err)
+		}
+		for _, k := range []string{"rust", "go"} {
+			q, err := d.GetQuery(ctx, &db.GetQueryParams{Keywords: k, Location: "paris"})
+			if err != nil {
+				t.Fatalf("failed to get query: %v", err)
+			}
+			if q.GeoID != "105015875" || q.IntervalHours != 6 {
+				t.Errorf("expected %s query with the group's options, got geo id %q and interval %d", k, q.GeoID, q.IntervalHours)
+			}
+		}
+	})
+
+	t.Run("deleting a member deletes the group", func(t *testing.T) {
+		if err := j.DeleteQuery(context.Background(), "golang", "remote"); err != nil {
+			t.Fatalf("failed to delete query: %s", err)
+		}
+		if _, err := j.ListQueryGroup(ctx, id); !errors.Is(err, ErrQueryGroupNotFound) {
+			t.Errorf("expected ErrQueryGroupNotFound, got %v", err)
+		}
+	})
+}
+
+func TestCreateQueryGroupSlowScrape(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		// wantLeft is whether the group is stored within the context's deadline.
+		wantLeft bool
+	}{
+		// Like the server's DB timeout, the context expires before the initial scrapes end.
+		{name: "expired context", timeout: 50 * time.Millisecond},
+		{name: "context with time left", timeout: 5 * time.Second, wantLeft: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &deadlineStore{fakeStore: newFakeStore()}
+			j, jCloser := NewConfigurableJobber(l, store, &slowScraper{delay: 200 * time.Millisecond})
+			defer jCloser()
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			deadline, _ := ctx.Deadline()
+			id, done, err := j.CreateQueryGroup(ctx, []GroupQuery{{"golang", "berlin"}, {"rust", "paris"}}, QueryOptions{})
+			if err != nil {
+				t.Fatalf("failed to create query group: %v", err)
+			}
+			if !done {
+				t.Errorf("expected the initial scrapes to complete")
+			}
+			queries, err := j.ListQueryGroup(context.Background(), id)
+			if err != nil {
+				t.Fatalf("failed to list query group: %v", err)
+			}
+			if len(queries) != 2 {
+				t.Errorf("expected 2 queries in the group, got %d", len(queries))
+			}
+			// Recomputing the time left moves the deadline by a few nanoseconds,
+			// far less than the scrapes' delay.
+			if gotLeft := !store.deadline.After(deadline.Add(50 * time.Millisecond)); gotLeft != tt.wantLeft {
+				t.Errorf("expected the group stored within the context's deadline to be %t, got deadline %v for %v", tt.wantLeft, store.deadline, deadline)
+			}
+		})
+	}
+}
+
+// deadlineStore fails the group writes on expired contexts, like the DB does,
+// and keeps the deadline the group was stored with.
+type deadlineStore struct {
+	*fakeStore
+	deadline time.Time
+}
+
+func (s *deadlineStore) CreateQueryGroup(ctx context.Context, memberIds string) (*db.QueryGroup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.deadline, _ = ctx.Deadline()
+	return s.fakeStore.CreateQueryGroup(ctx, memberIds)
+}
+
+func (s *deadlineStore) CreateQueryGroupMember(ctx context.Context, arg *db.CreateQueryGroupMemberParams) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.fakeStore.CreateQueryGroupMember(ctx, arg)
+}
+
+func TestCreateQueryInitialScrapeTimeout(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	s := &blockingScraper{started: make(chan struct{}, 1), release: make(chan struct{})}
+	timeout := 50 * time.Millisecond
+	j, jCloser := NewConfigurableJobber(l, d, s, WithInitialScrapeTimeout(timeout), WithShutdownTimeout(timeout))
+	defer jCloser()
+	defer close(s.release)
+
+	start := time.Now()
+	done, err := j.CreateQuery(context.Background(), "cuak", "squeek", QueryOptions{})
+	if err != nil {
+		t.Fatalf("failed to create query: %s", err)
+	}
+	if done {
+		t.Errorf("expected the initial scrape not to complete")
+	}
+	if elapsed := time.Since(start); elapsed > timeout+time.Second {
+		t.Errorf("expected CreateQuery to return after the initial scrape timeout, took %v", elapsed)
+	}
+	if !j.Gathering("cuak", "squeek") {
+		t.Errorf("expected the query to be gathering while its initial scrape runs")
+	}
+}
+
+func TestGathering(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	t.Run("cleared when the run returns early", func(t *testing.T) {
+		j, jCloser := NewConfigurableJobber(l, &missingQueryStore{fakeStore: newFakeStore()}, &offersScraper{})
+		defer jCloser()
+		done, err := j.CreateQuery(context.Background(), "cuak", "squeek", QueryOptions{})
+		if err != nil {
+			t.Fatalf("failed to create query: %s", err)
+		}
+		if !done {
+			t.Errorf("expected the initial run to complete")
+		}
+		if j.Gathering("cuak", "squeek") {
+			t.Errorf("expected the query not to be gathering once its run returned")
+		}
+	})
+
+	t.Run("keyed by keywords and location", func(t *testing.T) {
+		s := &blockingScraper{started: make(chan struct{}, 1), release: make(chan struct{})}
+		j, jCloser := NewConfigurableJobber(l, newFakeStore(), s, WithInitialScrapeTimeout(time.Millisecond))
+		defer jCloser()
+		defer close(s.release)
+		if _, err := j.CreateQuery(context.Background(), "go", "lang berlin", QueryOptions{}); err != nil {
+			t.Fatalf("failed to create query: %s", err)
+		}
+		if !j.Gathering("go", "lang berlin") {
+			t.Errorf("expected the query to be gathering while its initial scrape runs")
+		}
+		if j.Gathering("gol", "ang berlin") {
+			t.Errorf("expected another query with the same concatenation not to be gathering")
+		}
+	})
+}
+
+// missingQueryStore can't find the queries by ID, as if they were deleted right after being created.
+type missingQueryStore struct {
+	*fakeStore
+}
+
+func (s *missingQueryStore) GetQueryByID(context.Context, int64) (*db.Query, error) {
+	return nil, sql.ErrNoRows
+}
+
+func TestCreateQueryRepeatedRuns(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := NewConfigurableJobber(l, d, &failingScraper{err: &scrape.Error{Reason: scrape.ReasonExhausted, Err: scrape.ErrRetryable}})
+	defer jCloser()
+
+	if _, err := j.CreateQuery(context.Background(), "cuak", "squeek", QueryOptions{}); err != nil {
+		t.Fatalf("failed to create query: %s", err)
+	}
+	// The initial run's listener stays on the job, running it
+	// again must not signal the already completed creation.
+	for _, jb := range j.sched.Jobs() {
+		if !slices.Contains(jb.Tags(), queryKey{"cuak", "squeek"}.tag()) {
+			continue
+		}
+		for range 2 {
+			lastRun, _ := jb.LastRun() //nolint: errcheck
+			if err := jb.RunNow(); err != nil {
+				t.Fatalf("failed to run job: %v", err)
+			}
+			for range 100 {
+				if lr, _ := jb.LastRun(); lr.After(lastRun) { //nolint: errcheck
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+	// Let the last run's listener fire.
+	time.Sleep(50 * time.Millisecond)
+}
+
+func TestQueryCron(t *testing.T) {
+	createdAt := pgtype.Timestamptz{Time: time.Date(2025, 11, 13, 10, 25, 0, 0, time.UTC), Valid: true}
+	tests := []struct {
+		interval int32
+		want     string
+	}{
+		{interval: 0, want: "25 * * * *"}, // Queries created before intervals existed.
+		{interval: 1, want: "25 * * * *"},
+		{interval: 6, want: "25 */6 * * *"},
+		{interval: 24, want: "25 */24 * * *"},
+	}
+	for _, tt := range tests {
+		got := queryCron(&db.Query{CreatedAt: createdAt, IntervalHours: tt.interval})
+		if got != tt.want {
+			t.Errorf("wanted cron %q for a %d hours interval, got %q", tt.want, tt.interval, got)
+		}
+	}
+}
+
+func TestMergeOffers(t *testing.T) {
+	now := time.Now()
+	offer := func(source, id string, age time.Duration) *db.Offer {
+		return &db.Offer{Source: source, ID: id, PostedAt: pgtype.Timestamptz{Time: now.Add(-age), Valid: true}}
+	}
+	shared := offer("linkedin", "shared", time.Hour)
+
+	tests := []struct {
+		name  string
+		lists [][]*db.Offer
+		want  []string
+	}{
+		{
+			name:  "single query",
+			lists: [][]*db.Offer{{offer("linkedin", "a", time.Hour), offer("linkedin", "b", 2*time.Hour)}},
+			want:  []string{"linkedin/a", "linkedin/b"},
+		},
+		{
+			name:  "offer in two queries",
+			lists: [][]*db.Offer{{offer("linkedin", "a", 0), shared}, {shared, offer("linkedin", "b", 2*time.Hour)}},
+			want:  []string{"linkedin/a", "linkedin/shared", "linkedin/b"},
+		},
+		{
+			name:  "same id in other sources",
+			lists: [][]*db.Offer{{offer("linkedin", "a", time.Hour)}, {offer("indeed", "a", 0)}},
+			want:  []string{"indeed/a", "linkedin/a"},
+		},
+		{name: "no offers", lists: [][]*db.Offer{nil, {}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, o := range MergeOffers(tt.lists...) {
+				got = append(got, o.Source+"/"+o.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("wanted offers %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name                       string
+		keywords, location         string
+		wantKeywords, wantLocation string
+	}{
+		{name: "canonical", keywords: "golang", location: "berlin", wantKeywords: "golang", wantLocation: "berlin"},
+		{name: "case", keywords: "GoLang", location: "Berlin", wantKeywords: "golang", wantLocation: "berlin"},
+		{name: "surrounding whitespace", keywords: "  golang\t", location: "\nberlin ", wantKeywords: "golang", wantLocation: "berlin"},
+		{name: "inner whitespace", keywords: "senior   golang\tdev", location: "new  york", wantKeywords: "senior golang dev", wantLocation: "new york"},
+		{name: "surrounding separators", keywords: "golang,", location: " 'berlin'; ", wantKeywords: "golang", wantLocation: "berlin"},
+		{name: "symbols and dots are kept", keywords: "c# .net c++", location: "st. gallen", wantKeywords: "c# .net c++", wantLocation: "st. gallen"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, l := canonicalize(tt.keywords, tt.location)
+			if k != tt.wantKeywords || l != tt.wantLocation {
+				t.Errorf("wanted %q, %q, got %q, %q", tt.wantKeywords, tt.wantLocation, k, l)
+			}
+		})
+	}
+}
+
+func TestCanonicalQueries(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+
+	before, err := d.ListQueries(context.Background())
+	if err != nil {
+		t.Fatalf("unable to list queries: %v", err)
+	}
+	// The seed has a golang in berlin query.
+	for _, v := range [][2]string{{" GoLang ", "Berlin,"}, {"golang", "  berlin\t"}} {
+		if _, err := j.CreateQuery(context.Background(), v[0], v[1], QueryOptions{}); !errors.Is(err, ErrQueryExists) {
+			t.Fatalf("wanted %q in %q to exist, got %v", v[0], v[1], err)
+		}
+		if _, err := j.ListOffers(context.Background(), v[0], v[1], 0, 0); err != nil {
+			t.Errorf("wanted %q in %q to list the seed query's offers, got error: %v", v[0], v[1], err)
+		}
+	}
+	after, err := d.ListQueries(context.Background())
+	if err != nil {
+		t.Fatalf("unable to list queries: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("wanted the variants to map to the existing query, got %d queries, had %d", len(after), len(before))
+	}
+}
+
 func TestListOffers(t *testing.T) {
 	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
 	d, dbCloser := db.NewTestDB(t)
@@ -137,13 +559,13 @@ func TestListOffers(t *testing.T) {
 			name:     "invalid query with no offers",
 			keywords: "cuak",
 			location: "squeek",
-			wantErr:  sql.ErrNoRows,
+			wantErr:  ErrQueryNotFound,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			o, err := j.ListOffers(tt.keywords, tt.location)
+			o, err := j.ListOffers(context.Background(), tt.keywords, tt.location, 0, 0)
 			switch {
 			case err == nil:
 				if len(o) != tt.wantOffers {
@@ -158,47 +580,1431 @@ func TestListOffers(t *testing.T) {
 	}
 }
 
-func TestRunQuery(t *testing.T) {
+func TestNotFound(t *testing.T) {
 	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
 	d, dbCloser := db.NewTestDB(t)
 	defer dbCloser()
-	mockScraper := scrape.MockScraper
-	j, jCloser := NewConfigurableJobber(l, d, mockScraper)
+	j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper)
 	defer jCloser()
+	ctx := context.Background()
 
-	t.Run("with valid query", func(t *testing.T) {
-		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
-		if err != nil {
-			t.Errorf("unable to retrieve seed query: %v", err)
-		}
-		j.runQuery(q.ID)
+	tests := []struct {
+		name    string
+		call    func() error
+		wantErr error
+	}{
+		{name: "list offers", call: func() error { _, err := j.ListOffers(ctx, "cuak", "squeek", 0, 0); return err }, wantErr: ErrQueryNotFound},
+		{name: "touch query", call: func() error { return j.TouchQuery(ctx, "cuak", "squeek") }, wantErr: ErrQueryNotFound},
+		{name: "count offers", call: func() error { _, err := j.CountOffers(ctx, "cuak", "squeek"); return err }, wantErr: ErrQueryNotFound},
+		{name: "count offers by company", call: func() error { _, err := j.CountOffersByCompany(ctx, "cuak", "squeek"); return err }, wantErr: ErrQueryNotFound},
+		{name: "last error", call: func() error { _, err := j.LastError(ctx, "cuak", "squeek"); return err }, wantErr: ErrQueryNotFound},
+		{name: "run now", call: func() error { return j.RunNow(ctx, "cuak", "squeek") }, wantErr: ErrQueryNotFound},
+		{name: "set notification", call: func() error {
+			return j.SetNotification(ctx, "cuak", "squeek", NotificationPrefs{WebhookURL: "https://example.com/hook"})
+		}, wantErr: ErrQueryNotFound},
+		{name: "list query group", call: func() error { _, err := j.ListQueryGroup(ctx, 4242); return err }, wantErr: ErrQueryGroupNotFound},
+		{name: "get offer", call: func() error { _, err := j.GetOffer(ctx, scrape.SourceLinkedIn, "cuak"); return err }, wantErr: ErrOfferNotFound},
+	}
 
-		t.Run("it calls the scraper", func(t *testing.T) {
-			if *mockScraper.LastQuery != *q {
-				t.Errorf("wanted ran query to be %v, got %v", q, mockScraper.LastQuery)
-			}
-		})
-		t.Run("it updates the UpdatedAt field used for removing old queries", func(t *testing.T) {
-			qq, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
-			if err != nil {
-				t.Errorf("unable to retrieve seed query: %v", err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
 			}
-			if q.UpdatedAt.Time.After(qq.UpdatedAt.Time) {
-				t.Errorf("wanted the query initial UpdatedAt value to be before the new value")
+			if errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("expected the DB error not to leak, got %v", err)
 			}
 		})
-		// TODO: test adding offer and ignoring existing offer
-	})
+	}
+}
 
-	t.Run("with older than 7 days query deletes the query", func(t *testing.T) {
+func TestQueriedAtThrottle(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+
+	// The seed's python in san francisco query was last read 8 days ago.
+	queriedAt := func() time.Time {
 		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "python", Location: "san francisco"})
 		if err != nil {
-			t.Errorf("unable to retrieve seed query: %v", err)
+			t.Fatalf("unable to retrieve seed query: %v", err)
 		}
-		j.runQuery(q.ID)
-		_, err = d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "python", Location: "san francisco"})
-		if !errors.Is(err, sql.ErrNoRows) {
-			t.Errorf("query should have been deleted but got: %v", err)
+		return q.QueriedAt.Time
+	}
+	seeded := queriedAt()
+
+	if _, err := j.ListOffers(context.Background(), "python", "san francisco", 0, 0); err != nil {
+		t.Fatalf("unable to list offers: %v", err)
+	}
+	first := queriedAt()
+	if !first.After(seeded) {
+		t.Errorf("wanted the first read to update queried_at, got %v", first)
+	}
+	if err := j.TouchQuery(context.Background(), "python", "san francisco"); err != nil {
+		t.Fatalf("unable to touch query: %v", err)
+	}
+	if _, err := j.ListOffers(context.Background(), "python", "san francisco", 0, 0); err != nil {
+		t.Fatalf("unable to list offers: %v", err)
+	}
+	if got := queriedAt(); !got.Equal(first) {
+		t.Errorf("wanted the reads within the throttle not to update queried_at, got %v instead of %v", got, first)
+	}
+}
+
+func TestListOffersPagination(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+
+	ctx := context.Background()
+	q, err := d.CreateQuery(ctx, &db.CreateQueryParams{Keywords: "paginated", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to create query: %v", err)
+	}
+	// Offers are listed most recent first: page-0, page-1...
+	var all []string
+	for i := range DefaultOffersLimit + 5 {
+		o := &db.CreateOfferParams{
+			ID:       fmt.Sprintf("page-%d", i),
+			Source:   scrape.SourceLinkedIn,
+			Title:    "Gopher",
+			PostedAt: pgtype.Timestamptz{Time: time.Now().Add(-time.Duration(i) * time.Minute), Valid: true},
 		}
-	})
+		if _, err := d.CreateOffer(ctx, o); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+		if err := d.CreateQueryOfferAssoc(ctx, &db.CreateQueryOfferAssocParams{QueryID: q.ID, OfferSource: o.Source, OfferID: o.ID}); err != nil {
+			t.Fatalf("unable to create query offer association: %v", err)
+		}
+		all = append(all, o.ID)
+	}
+
+	tests := []struct {
+		name    string
+		limit   int
+		offset  int
+		wantIDs []string
+	}{
+		{name: "no limit returns the default limit", wantIDs: all[:DefaultOffersLimit]},
+		{name: "limit is honored", limit: 3, wantIDs: all[:3]},
+		{name: "offset paginates", limit: 3, offset: 3, wantIDs: all[3:6]},
+		{name: "last page is partial", limit: 3, offset: len(all) - 2, wantIDs: all[len(all)-2:]},
+		{name: "offset past the end returns nothing", limit: 3, offset: len(all)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offers, err := j.ListOffers(ctx, "paginated", "berlin", tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var gotIDs []string
+			for _, o := range offers {
+				gotIDs = append(gotIDs, o.ID)
+			}
+			if !slices.Equal(tt.wantIDs, gotIDs) {
+				t.Errorf("expected offers %v, got %v", tt.wantIDs, gotIDs)
+			}
+		})
+	}
+}
+
+func TestDeleteQuery(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+
+	t.Run("deletes an existing query and unschedules it", func(t *testing.T) {
+		if err := j.DeleteQuery(context.Background(), "golang", "berlin"); err != nil {
+			t.Fatalf("failed to delete query: %s", err)
+		}
+		_, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("query should have been deleted but got: %v", err)
+		}
+		for _, jb := range j.sched.Jobs() {
+			if slices.Contains(jb.Tags(), queryKey{"golang", "berlin"}.tag()) {
+				t.Errorf("expected deleted query job to be unscheduled")
+			}
+		}
+		wantJobs := 4 // Four queries from DB seed - deleted query + old offers deletetion.
+		if gotJobs := len(j.sched.Jobs()); wantJobs != gotJobs {
+			t.Errorf("wanted %d jobs, got %d", wantJobs, gotJobs)
+		}
+	})
+
+	t.Run("non existing query returns ErrQueryNotFound", func(t *testing.T) {
+		if err := j.DeleteQuery(context.Background(), "cuak", "squeek"); !errors.Is(err, ErrQueryNotFound) {
+			t.Errorf("expected ErrQueryNotFound, got: %v", err)
+		}
+	})
+}
+
+func TestRunQuery(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	mockScraper := scrape.MockScraper
+	j, jCloser := NewConfigurableJobber(l, d, mockScraper)
+	defer jCloser()
+
+	t.Run("with valid query", func(t *testing.T) {
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+		if err != nil {
+			t.Errorf("unable to retrieve seed query: %v", err)
+		}
+		scraperJob := func() uint64 {
+			m := &dto.Metric{}
+			h := metrics.ScraperJob.WithLabelValues(mockScraper.Name(), "golang", "berlin", "0").(prometheus.Histogram)
+			if err := h.Write(m); err != nil {
+				t.Fatalf("unable to read scraper job metric: %v", err)
+			}
+			return m.GetHistogram().GetSampleCount()
+		}
+		before := scraperJob()
+		j.runQuery(q.ID)
+
+		t.Run("it calls the scraper", func(t *testing.T) {
+			if *mockScraper.LastQuery != *q {
+				t.Errorf("wanted ran query to be %v, got %v", q, mockScraper.LastQuery)
+			}
+		})
+		t.Run("it records the scraper job", func(t *testing.T) {
+			if got := scraperJob() - before; got != 1 {
+				t.Errorf("wanted 1 scraper job sample, got %d", got)
+			}
+		})
+		t.Run("it updates the UpdatedAt field used for removing old queries", func(t *testing.T) {
+			qq, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+			if err != nil {
+				t.Errorf("unable to retrieve seed query: %v", err)
+			}
+			if q.UpdatedAt.Time.After(qq.UpdatedAt.Time) {
+				t.Errorf("wanted the query initial UpdatedAt value to be before the new value")
+			}
+		})
+		// TODO: test adding offer and ignoring existing offer
+	})
+
+	t.Run("with older than 7 days query deletes the query", func(t *testing.T) {
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "python", Location: "san francisco"})
+		if err != nil {
+			t.Errorf("unable to retrieve seed query: %v", err)
+		}
+		expired := func() float64 {
+			m := &dto.Metric{}
+			if err := metrics.JobberExpiredQueries.WithLabelValues("python", "san francisco").Write(m); err != nil {
+				t.Fatalf("unable to read expired queries metric: %v", err)
+			}
+			return m.GetCounter().GetValue()
+		}
+		scheduled := func() float64 {
+			m := &dto.Metric{}
+			if err := metrics.JobberScheduledQueries.WithLabelValues(fmt.Sprintf("%d", q.ID), queryKey{q.Keywords, q.Location}.tag(), queryCron(q)).Write(m); err != nil {
+				t.Fatalf("unable to read scheduled queries metric: %v", err)
+			}
+			return m.GetGauge().GetValue()
+		}
+		before, beforeScheduled := expired(), scheduled()
+		j.runQuery(q.ID)
+		// Other tests' jobbers schedule the same seed query, so we check the
+		// gauge of the labels it was scheduled with drops by one.
+		if got := beforeScheduled - scheduled(); got != 1 {
+			t.Errorf("wanted the scheduled queries gauge to decrement by 1, got %v", got)
+		}
+		_, err = d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "python", Location: "san francisco"})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("query should have been deleted but got: %v", err)
+		}
+		// Runs of the deleted query don't count it again.
+		j.runQuery(q.ID)
+		if got := expired() - before; got != 1 {
+			t.Errorf("wanted the expired queries counter to increment by 1, got %v", got)
+		}
+	})
+}
+
+func TestRunQueryScrapeErrors(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	s := &failingScraper{err: &scrape.Error{Reason: scrape.ReasonExhausted, Err: scrape.ErrRetryable}}
+	j := &Jobber{ctx: context.Background(), logger: l, db: d, scpr: s, scrapes: make(chan struct{}, 1), seen: newSeenCache(0), circuit: newCircuit(0, 0, 0)}
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+
+	counter := func() float64 {
+		m := &dto.Metric{}
+		if err := metrics.ScraperErrors.WithLabelValues("mock", scrape.ReasonExhausted).Write(m); err != nil {
+			t.Fatalf("unable to read scraper errors metric: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	before := counter()
+	j.runQuery(q.ID)
+	if got := counter() - before; got != 1 {
+		t.Errorf("wanted the scraper errors counter to increment by 1, got %v", got)
+	}
+}
+
+type failingScraper struct {
+	err error
+}
+
+func (s *failingScraper) Scrape(context.Context, *db.Query) ([]db.CreateOfferParams, error) {
+	return nil, s.err
+}
+
+func (s *failingScraper) Name() string { return "mock" }
+
+func TestRunQueryLastError(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	s := &failingScraper{err: errors.New("linkedin is down")}
+	j := &Jobber{ctx: context.Background(), logger: l, db: d, scpr: s, scrapes: make(chan struct{}, 1), seen: newSeenCache(0), circuit: newCircuit(0, 0, 0)}
+
+	lastError := func() (string, bool) {
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+		if err != nil {
+			t.Fatalf("unable to retrieve seed query: %v", err)
+		}
+		return q.LastError, q.LastErrorAt.Valid
+	}
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+
+	t.Run("a failing run records the error", func(t *testing.T) {
+		j.runQuery(q.ID)
+		if got, at := lastError(); got != s.err.Error() || !at {
+			t.Errorf("wanted last error %q with its time, got %q (time set: %t)", s.err.Error(), got, at)
+		}
+		failedAt, err := j.LastError(context.Background(), "golang", "berlin")
+		if err != nil {
+			t.Fatalf("unable to get last error: %v", err)
+		}
+		if failedAt.IsZero() {
+			t.Error("wanted the time of the failed run")
+		}
+	})
+
+	t.Run("a successful run clears it", func(t *testing.T) {
+		j.scpr = &offersScraper{}
+		j.runQuery(q.ID)
+		if got, at := lastError(); got != "" || at {
+			t.Errorf("wanted no last error, got %q (time set: %t)", got, at)
+		}
+		failedAt, err := j.LastError(context.Background(), "golang", "berlin")
+		if err != nil {
+			t.Fatalf("unable to get last error: %v", err)
+		}
+		if !failedAt.IsZero() {
+			t.Errorf("wanted no failed run, got %v", failedAt)
+		}
+	})
+}
+
+func TestDeleteOldOffers(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	// We skip the constructor so the offers aren't deleted before the test does.
+	j := &Jobber{
+		ctx:            context.Background(),
+		logger:         l,
+		db:             d,
+		offerRetention: defaultOfferRetention,
+		portalRetention: map[string]time.Duration{
+			scrape.SourceLinkedIn: 30 * 24 * time.Hour,
+			scrape.SourceRemoteOK: 24 * time.Hour,
+		},
+	}
+
+	tests := []struct {
+		source    string
+		id        string
+		postedAgo time.Duration
+		wantKept  bool
+	}{
+		{source: scrape.SourceLinkedIn, id: "linkedin-recent", postedAgo: 10 * 24 * time.Hour, wantKept: true},
+		{source: scrape.SourceLinkedIn, id: "linkedin-expired", postedAgo: 31 * 24 * time.Hour, wantKept: false},
+		{source: scrape.SourceRemoteOK, id: "remoteok-recent", postedAgo: time.Hour, wantKept: true},
+		{source: scrape.SourceRemoteOK, id: "remoteok-expired", postedAgo: 2 * 24 * time.Hour, wantKept: false},
+		{source: "indeed", id: "indeed-recent", postedAgo: 6 * 24 * time.Hour, wantKept: true},
+		{source: "indeed", id: "indeed-expired", postedAgo: 8 * 24 * time.Hour, wantKept: false},
+	}
+	for _, tt := range tests {
+		if _, err := d.CreateOffer(context.Background(), &db.CreateOfferParams{
+			ID:       tt.id,
+			Source:   tt.source,
+			Title:    "Golang Developer",
+			Company:  "Acme",
+			Location: "Berlin",
+			PostedAt: pgtype.Timestamptz{Time: time.Now().Add(-tt.postedAgo), Valid: true},
+		}); err != nil {
+			t.Fatalf("unable to create offer %s: %v", tt.id, err)
+		}
+	}
+
+	if err := j.deleteOldOffers(); err != nil {
+		t.Fatalf("unable to delete old offers: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			_, err := d.GetOffer(context.Background(), &db.GetOfferParams{Source: tt.source, ID: tt.id})
+			if gotKept := err == nil; gotKept != tt.wantKept {
+				t.Errorf("wanted offer kept to be %t, got error: %v", tt.wantKept, err)
+			}
+		})
+	}
+}
+
+func TestCleanupCron(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+
+	tests := []struct {
+		name       string
+		cron       string
+		wantHour   int
+		wantMinute int
+	}{
+		{name: "default", wantHour: 2},
+		{name: "custom", cron: "30 4 * * *", wantHour: 4, wantMinute: 30},
+		{name: "invalid falls back to the default", cron: "every night", wantHour: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.cron != "" {
+				opts = append(opts, WithCleanupCron(tt.cron))
+			}
+			j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper, opts...)
+			defer jCloser()
+
+			i := slices.IndexFunc(j.sched.Jobs(), func(jb gocron.Job) bool { return jb.Name() == cleanupJobName })
+			if i == -1 {
+				t.Fatal("wanted the cleanup job to be scheduled")
+			}
+			// The first run is right away, the next one follows the cron.
+			runs, err := j.sched.Jobs()[i].NextRuns(2)
+			if err != nil {
+				t.Fatalf("unable to get the cleanup job's next runs: %v", err)
+			}
+			next := runs[len(runs)-1]
+			if next.Hour() != tt.wantHour || next.Minute() != tt.wantMinute {
+				t.Errorf("wanted the cleanup at %02d:%02d, got %s", tt.wantHour, tt.wantMinute, next.Format(time.TimeOnly))
+			}
+		})
+	}
+}
+
+func TestPause(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	scpr := &countingScraper{}
+	j, jCloser := NewConfigurableJobber(l, d, scpr, WithInitialScrapeTimeout(100*time.Millisecond))
+	defer jCloser()
+
+	if err := j.Pause(); err != nil {
+		t.Fatalf("unable to pause: %v", err)
+	}
+	if !j.Paused() {
+		t.Error("wanted the jobber to be paused")
+	}
+
+	t.Run("no jobs run while paused", func(t *testing.T) {
+		done, err := j.CreateQuery(context.Background(), "paused", "berlin", QueryOptions{})
+		if err != nil {
+			t.Fatalf("unable to create query: %v", err)
+		}
+		if done {
+			t.Error("wanted the initial scrape to be skipped")
+		}
+		if _, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "paused", Location: "berlin"}); err != nil {
+			t.Errorf("wanted the query to be persisted, got: %v", err)
+		}
+		if err := j.RunNow(context.Background(), "golang", "berlin"); err != nil {
+			t.Fatalf("unable to run query: %v", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if got := scpr.calls.Load(); got != 0 {
+			t.Errorf("wanted no scrapes while paused, got %d", got)
+		}
+	})
+
+	t.Run("resuming runs the pending jobs", func(t *testing.T) {
+		j.Resume()
+		if j.Paused() {
+			t.Error("wanted the jobber to be resumed")
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for scpr.calls.Load() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := scpr.calls.Load(); got == 0 {
+			t.Error("wanted the query run once resumed")
+		}
+	})
+}
+
+func TestCircuit(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		const (
+			window   = time.Hour
+			cooldown = 30 * time.Minute
+		)
+		c := newCircuit(0.5, window, cooldown)
+		paused := func() float64 {
+			m := &dto.Metric{}
+			if err := metrics.ScraperPaused.WithLabelValues("flaky").Write(m); err != nil {
+				t.Fatalf("unable to read scraper paused metric: %v", err)
+			}
+			return m.GetGauge().GetValue()
+		}
+
+		// 3 of 5 runs failing is past the 50% threshold.
+		for _, failed := range []bool{false, true, false, true, true} {
+			if !c.allow("flaky") {
+				t.Fatalf("expected scraping to be allowed before reaching the threshold")
+			}
+			c.record("flaky", failed)
+		}
+		if c.allow("flaky") {
+			t.Errorf("expected scraping to be paused past the threshold")
+		}
+		if paused() != 1 {
+			t.Errorf("expected scraper paused gauge to be 1, got %v", paused())
+		}
+		if !c.allow("other") {
+			t.Errorf("expected other portals not to be paused")
+		}
+
+		// Failed probe pauses it again.
+		time.Sleep(cooldown)
+		if !c.allow("flaky") {
+			t.Fatalf("expected a probe after the cooldown")
+		}
+		if c.allow("flaky") {
+			t.Errorf("expected a single probe at a time")
+		}
+		c.record("flaky", true)
+		if c.allow("flaky") {
+			t.Errorf("expected scraping to be paused after a failed probe")
+		}
+
+		// Successful probe resumes it.
+		time.Sleep(cooldown)
+		if !c.allow("flaky") {
+			t.Fatalf("expected a probe after the cooldown")
+		}
+		c.record("flaky", false)
+		if !c.allow("flaky") {
+			t.Errorf("expected scraping to resume after a successful probe")
+		}
+		if paused() != 0 {
+			t.Errorf("expected scraper paused gauge to be 0, got %v", paused())
+		}
+
+		// Failures outside the window are forgotten.
+		for range circuitMinRuns - 1 {
+			c.record("flaky", true)
+		}
+		time.Sleep(window + time.Minute)
+		c.record("flaky", true)
+		if !c.allow("flaky") {
+			t.Errorf("expected old failures not to pause scraping")
+		}
+	})
+}
+
+func TestObserveOffersPerQuery(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	// We skip the constructor so the old offers aren't deleted before observing.
+	j := &Jobber{ctx: context.Background(), logger: l, db: d}
+
+	before := offersPerQuery(t)
+	j.observeOffersPerQuery()
+	after := offersPerQuery(t)
+
+	// The seed has 4 queries with 2, 0, 1 and 0 offers.
+	if got := after.GetSampleCount() - before.GetSampleCount(); got != 4 {
+		t.Errorf("wanted 4 observations, got %d", got)
+	}
+	if got := after.GetSampleSum() - before.GetSampleSum(); got != 3 {
+		t.Errorf("wanted observations to sum 3 offers, got %v", got)
+	}
+}
+
+func TestActiveJobsMetric(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+
+	activeJobs := func() float64 {
+		m := &dto.Metric{}
+		if err := metrics.JobberActiveJobs.Write(m); err != nil {
+			t.Fatalf("unable to read active jobs metric: %v", err)
+		}
+		return m.GetGauge().GetValue()
+	}
+	// The seed's 4 queries + old offers deletion + offers per query observation.
+	if got := activeJobs(); got != 6 || int(got) != len(j.sched.Jobs()) {
+		t.Errorf("wanted 6 active jobs, got %v with %d scheduled", got, len(j.sched.Jobs()))
+	}
+	if err := j.DeleteQuery(context.Background(), "golang", "berlin"); err != nil {
+		t.Fatalf("failed to delete query: %v", err)
+	}
+	if got := activeJobs(); got != 5 {
+		t.Errorf("wanted 5 active jobs after deleting a query, got %v", got)
+	}
+}
+
+func offersPerQuery(t *testing.T) *dto.Histogram {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := metrics.JobberOffersPerQuery.Write(m); err != nil {
+		t.Fatalf("unable to read offers per query metric: %v", err)
+	}
+	return m.GetHistogram()
+}
+
+func TestMaxConcurrentScrapes(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	scpr := &countingScraper{delay: 50 * time.Millisecond}
+	j, jCloser := NewConfigurableJobber(l, d, scpr, WithMaxConcurrentScrapes(2))
+	defer jCloser()
+
+	queries, err := d.ListQueries(context.Background())
+	if err != nil {
+		t.Fatalf("unable to list seed queries: %v", err)
+	}
+	// The seed has 3 queries in use, plus one older than 7 days that isn't scraped.
+	for i := range 3 {
+		q, err := d.CreateQuery(context.Background(), &db.CreateQueryParams{Keywords: "concurrent", Location: strings.Repeat("x", i+1)})
+		if err != nil {
+			t.Fatalf("unable to create query: %v", err)
+		}
+		queries = append(queries, q)
+	}
+
+	var wg sync.WaitGroup
+	for _, q := range queries {
+		wg.Go(func() { j.runQuery(q.ID) })
+	}
+	wg.Wait()
+
+	if got := scpr.calls.Load(); got != 6 {
+		t.Errorf("wanted 6 scrapes, got %d", got)
+	}
+	if got := scpr.max.Load(); got > 2 {
+		t.Errorf("wanted at most 2 concurrent scrapes, got %d", got)
+	}
+}
+
+func TestQueuedRunsMetric(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	s := &slowScraper{delay: 500 * time.Millisecond, started: make(chan struct{}, 1)}
+	j, jCloser := NewConfigurableJobber(l, d, s, WithMaxConcurrentScrapes(1))
+	defer jCloser()
+
+	queries, err := d.ListQueries(context.Background())
+	if err != nil {
+		t.Fatalf("unable to list seed queries: %v", err)
+	}
+	var wg sync.WaitGroup
+	wg.Go(func() { j.runQuery(queries[1].ID) })
+	<-s.started
+	// The slow scrape holds the only slot, the next runs queue up.
+	for _, q := range queries[2:] {
+		wg.Go(func() { j.runQuery(q.ID) })
+	}
+
+	want := float64(len(queries[2:]))
+	var got float64
+	for range 20 {
+		m := &dto.Metric{}
+		if err := metrics.JobberQueuedRuns.Write(m); err != nil {
+			t.Fatalf("failed to write metric: %v", err)
+		}
+		if got = m.GetGauge().GetValue(); got == want {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got != want {
+		t.Errorf("wanted %v queued runs while saturated, got %v", want, got)
+	}
+
+	wg.Wait()
+	m := &dto.Metric{}
+	if err := metrics.JobberQueuedRuns.Write(m); err != nil {
+		t.Fatalf("failed to write metric: %v", err)
+	}
+	if got := m.GetGauge().GetValue(); got != 0 {
+		t.Errorf("wanted no queued runs once done, got %v", got)
+	}
+}
+
+func TestNewOffersMetric(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	s := &offersScraper{offers: []db.CreateOfferParams{
+		{ID: "existing_offer", Title: "Junior Golang Dweeb", Company: "Späti GmbH", Source: "linkedin", PostedAt: now},
+		{ID: "new_offer_1", Title: "Gopher", Company: "Acme", Source: "linkedin", PostedAt: now},
+		{ID: "new_offer_2", Title: "Gopher", Company: "Globex", Source: "linkedin", PostedAt: now},
+	}}
+	j, jCloser := NewConfigurableJobber(l, d, s)
+	defer jCloser()
+
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+	newOffers := func() float64 {
+		m := &dto.Metric{}
+		if err := metrics.JobberNewOffers.WithLabelValues("golang", "berlin").Write(m); err != nil {
+			t.Fatalf("failed to write metric: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	before := newOffers()
+	j.runQuery(q.ID)
+	if got := newOffers() - before; got != 2 {
+		t.Errorf("wanted the metric to count 2 new offers, got %v", got)
+	}
+
+	// Running it again finds no new offers.
+	j.runQuery(q.ID)
+	if got := newOffers() - before; got != 2 {
+		t.Errorf("wanted the metric not to count known offers, got %v new offers", got)
+	}
+}
+
+func TestRunQueryLog(t *testing.T) {
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		offers    []db.CreateOfferParams
+		wantLevel string
+	}{
+		{
+			name: "offers found are logged at info",
+			offers: []db.CreateOfferParams{
+				{ID: "log_offer_1", Title: "Gopher", Company: "Acme", Source: "linkedin", PostedAt: now},
+				{ID: "log_offer_2", Title: "Gopher", Company: "Globex", Source: "linkedin", PostedAt: now},
+			},
+			wantLevel: slog.LevelInfo.String(),
+		},
+		{name: "no offers found are logged at debug", wantLevel: slog.LevelDebug.String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			l := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			s := &offersScraper{offers: tt.offers}
+			j := &Jobber{ctx: context.Background(), logger: l, db: d, scpr: s, scrapes: make(chan struct{}, 1), seen: newSeenCache(0), circuit: newCircuit(0, 0, 0)}
+			j.runQuery(q.ID)
+
+			var entry map[string]any
+			for line := range strings.Lines(logs.String()) {
+				var e map[string]any
+				if err := json.Unmarshal([]byte(line), &e); err != nil {
+					t.Fatalf("unable to decode log line %q: %v", line, err)
+				}
+				if e[slog.MessageKey] == "successfuly completed jobber.runQuery" {
+					entry = e
+				}
+			}
+			if entry == nil {
+				t.Fatalf("wanted a log of the completed run, got logs: %s", logs.String())
+			}
+			if entry[slog.LevelKey] != tt.wantLevel {
+				t.Errorf("wanted level %s, got %v", tt.wantLevel, entry[slog.LevelKey])
+			}
+			if got, ok := entry["offers"].(float64); !ok || int(got) != len(tt.offers) {
+				t.Errorf("wanted %d offers logged, got %v", len(tt.offers), entry["offers"])
+			}
+			if _, ok := entry["elapsed"].(float64); !ok {
+				t.Errorf("wanted the elapsed time logged, got %v", entry["elapsed"])
+			}
+		})
+	}
+}
+
+// countingScraper keeps track of the maximum amount of scrapes running at the same time.
+type countingScraper struct {
+	delay   time.Duration
+	running atomic.Int32
+	max     atomic.Int32
+	calls   atomic.Int32
+}
+
+func (s *countingScraper) Scrape(context.Context, *db.Query) ([]db.CreateOfferParams, error) {
+	s.calls.Add(1)
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		m := s.max.Load()
+		if n <= m || s.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	time.Sleep(s.delay)
+	return nil, nil
+}
+
+func (s *countingScraper) Name() string { return "mock" }
+
+func TestShutdown(t *testing.T) {
+	var logs bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	t.Cleanup(dbCloser)
+	s := &slowScraper{delay: time.Minute, started: make(chan struct{}, 1), release: make(chan struct{})}
+	// The abandoned scrape finishes once the test is done, before the DB is closed.
+	t.Cleanup(func() { close(s.release) })
+	timeout := 100 * time.Millisecond
+	j, jCloser := NewConfigurableJobber(l, d, s, WithShutdownTimeout(timeout), WithDrainTimeout(timeout))
+
+	// Run a scheduled query now, the scrape ignores cancellation and blocks.
+	for _, jb := range j.sched.Jobs() {
+		if slices.Contains(jb.Tags(), queryKey{"golang", "berlin"}.tag()) {
+			if err := jb.RunNow(); err != nil {
+				t.Fatalf("failed to run job: %v", err)
+			}
+		}
+	}
+	<-s.started
+
+	start := time.Now()
+	jCloser()
+	if elapsed := time.Since(start); elapsed > 2*timeout+time.Second {
+		t.Errorf("expected shutdown to complete within the timeouts, took %v", elapsed)
+	}
+	if !strings.Contains(logs.String(), "abandoned running jobs") {
+		t.Errorf("expected a warning about the abandoned job, got logs: %s", logs.String())
+	}
+}
+
+// slowScraper blocks for the configured delay regardless of the context,
+// or until release is closed.
+type slowScraper struct {
+	delay   time.Duration
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowScraper) Scrape(context.Context, *db.Query) ([]db.CreateOfferParams, error) {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	select {
+	case <-time.After(s.delay):
+	case <-s.release:
+	}
+	return nil, nil
+}
+
+func (s *slowScraper) Name() string { return "mock" }
+
+func TestDrain(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	t.Run("closer waits for running queries to store their offers", func(t *testing.T) {
+		d, dbCloser := db.NewTestDB(t)
+		defer dbCloser()
+		s := &blockingScraper{
+			started: make(chan struct{}),
+			release: make(chan struct{}),
+			offers:  []db.CreateOfferParams{{ID: "drained", Title: "Drained", Company: "Acme", Location: "Berlin"}},
+		}
+		j, jCloser := NewConfigurableJobber(l, d, s, WithDrainTimeout(time.Minute))
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+		if err != nil {
+			t.Fatalf("unable to retrieve seed query: %v", err)
+		}
+		go j.runQuery(q.ID)
+		<-s.started
+
+		closed := make(chan struct{})
+		go func() {
+			jCloser()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+			t.Fatalf("expected the closer to block while the query runs")
+		case <-time.After(100 * time.Millisecond):
+		}
+		close(s.release)
+		<-closed
+
+		offers, err := d.ListOffers(context.Background(), &db.ListOffersParams{ID: q.ID})
+		if err != nil {
+			t.Fatalf("unable to list offers: %v", err)
+		}
+		if !slices.ContainsFunc(offers, func(o *db.Offer) bool { return o.ID == "drained" }) {
+			t.Errorf("expected the drained query's offer to be stored")
+		}
+	})
+
+	t.Run("closer gives up after the drain timeout", func(t *testing.T) {
+		d, dbCloser := db.NewTestDB(t)
+		defer dbCloser()
+		s := &blockingScraper{started: make(chan struct{}), release: make(chan struct{})}
+		defer close(s.release)
+		timeout := 100 * time.Millisecond
+		j, jCloser := NewConfigurableJobber(l, d, s, WithDrainTimeout(timeout))
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+		if err != nil {
+			t.Fatalf("unable to retrieve seed query: %v", err)
+		}
+		go j.runQuery(q.ID)
+		<-s.started
+
+		start := time.Now()
+		jCloser()
+		if elapsed := time.Since(start); elapsed < timeout || elapsed > timeout+time.Second {
+			t.Errorf("expected the closer to return after the drain timeout, took %v", elapsed)
+		}
+	})
+}
+
+func TestRunQueryDeletedMidScrape(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantOffer bool
+	}{
+		{name: "offers are discarded", wantOffer: false},
+		{name: "offers are stored without association", opts: []Option{WithStoreOrphanOffers()}, wantOffer: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			l := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))
+			d, dbCloser := db.NewTestDB(t)
+			defer dbCloser()
+			s := &blockingScraper{
+				started: make(chan struct{}),
+				release: make(chan struct{}),
+				offers:  []db.CreateOfferParams{{ID: "orphan", Source: scrape.SourceLinkedIn, Title: "Orphan", Company: "Nobody", Location: "Nowhere"}},
+			}
+			j, jCloser := NewConfigurableJobber(l, d, s, tt.opts...)
+			defer jCloser()
+
+			q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+			if err != nil {
+				t.Fatalf("unable to retrieve seed query: %v", err)
+			}
+			done := make(chan struct{})
+			go func() {
+				j.runQuery(q.ID)
+				close(done)
+			}()
+			<-s.started
+			if err := j.DeleteQuery(context.Background(), "golang", "berlin"); err != nil {
+				t.Fatalf("failed to delete query: %v", err)
+			}
+			close(s.release)
+			<-done
+
+			if strings.Contains(logs.String(), "level=ERROR") {
+				t.Errorf("expected no errors, got logs: %s", logs.String())
+			}
+			_, err = d.GetOffer(context.Background(), &db.GetOfferParams{Source: scrape.SourceLinkedIn, ID: "orphan"})
+			if gotOffer := err == nil; gotOffer != tt.wantOffer {
+				t.Errorf("wanted offer stored to be %t, got error: %v", tt.wantOffer, err)
+			}
+		})
+	}
+}
+
+func TestRunQueryPending(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	s := &blockingScraper{started: make(chan struct{}), release: make(chan struct{})}
+	j, jCloser := NewConfigurableJobber(l, d, s)
+	defer jCloser()
+
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		j.runQuery(q.ID)
+		close(done)
+	}()
+	<-s.started
+
+	// The next run returns without scraping while the previous one is pending,
+	// otherwise it'd block on the scraper's started channel.
+	skipped := make(chan struct{})
+	go func() {
+		j.runQuery(q.ID)
+		close(skipped)
+	}()
+	select {
+	case <-skipped:
+	case <-time.After(time.Second):
+		t.Fatal("wanted the run to be skipped while the previous one is pending")
+	}
+	close(s.release)
+	<-done
+
+	// Once the previous run finished, the query runs again.
+	ran := make(chan struct{})
+	go func() {
+		j.runQuery(q.ID)
+		close(ran)
+	}()
+	select {
+	case <-s.started:
+	case <-time.After(time.Second):
+		t.Fatal("wanted the query to run after the previous run finished")
+	}
+	<-ran
+}
+
+func TestRunQueryConcurrentOffer(t *testing.T) {
+	var logs syncBuffer
+	l := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	store := &upsertCountingStore{fakeStore: newFakeStore()}
+	offer := db.CreateOfferParams{ID: "shared", Title: "Gopher", Source: scrape.SourceLinkedIn, PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+	scpr := &blockingScraper{started: make(chan struct{}, 2), release: make(chan struct{}), offers: []db.CreateOfferParams{offer}}
+	j, jCloser := NewConfigurableJobber(l, store, scpr)
+	defer jCloser()
+	ctx := context.Background()
+
+	var ids []int64
+	for _, location := range []string{"berlin", "munich"} {
+		q, err := store.CreateQuery(ctx, &db.CreateQueryParams{Keywords: "golang", Location: location})
+		if err != nil {
+			t.Fatalf("unable to create query: %v", err)
+		}
+		ids = append(ids, q.ID)
+	}
+	// Both queries scrape the offer before either stores it.
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Go(func() { j.runQuery(id) })
+	}
+	<-scpr.started
+	<-scpr.started
+	close(scpr.release)
+	wg.Wait()
+
+	for _, id := range ids {
+		offers, err := store.ListOffers(ctx, &db.ListOffersParams{ID: id})
+		if err != nil {
+			t.Fatalf("unable to list offers: %v", err)
+		}
+		if len(offers) != 1 || offers[0].ID != offer.ID {
+			t.Errorf("wanted query %d associated to the shared offer, got %d offers", id, len(offers))
+		}
+	}
+	if store.upserts.Load() != 2 || store.inserted.Load() != 1 {
+		t.Errorf("wanted both queries to upsert the offer and one to insert it, got %d upserts and %d inserts", store.upserts.Load(), store.inserted.Load())
+	}
+	if strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("wanted the concurrent insert not to be logged as an error, got logs:\n%s", logs.String())
+	}
+}
+
+// upsertCountingStore counts the offers upserted, and the ones inserted by the upserts.
+type upsertCountingStore struct {
+	*fakeStore
+	upserts  atomic.Int32
+	inserted atomic.Int32
+}
+
+func (s *upsertCountingStore) UpsertOffer(ctx context.Context, arg *db.UpsertOfferParams) (bool, error) {
+	inserted, err := s.fakeStore.UpsertOffer(ctx, arg)
+	s.upserts.Add(1)
+	if inserted {
+		s.inserted.Add(1)
+	}
+	return inserted, err
+}
+
+// syncBuffer is a bytes.Buffer safe for the concurrent writes of the runs' logs.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// blockingScraper signals when the scrape starts and blocks until released.
+type blockingScraper struct {
+	started chan struct{}
+	release chan struct{}
+	offers  []db.CreateOfferParams
+}
+
+func (s *blockingScraper) Scrape(context.Context, *db.Query) ([]db.CreateOfferParams, error) {
+	s.started <- struct{}{}
+	<-s.release
+	// Like the scrapers, every scrape gets its own offers, which runQuery modifies.
+	return slices.Clone(s.offers), nil
+}
+
+func (s *blockingScraper) Name() string { return "mock" }
+
+func TestNotifications(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	s := &offersScraper{offers: []db.CreateOfferParams{
+		{ID: "existing_offer", Title: "Junior Golang Dweeb", Company: "Späti GmbH", Location: "Remote", PostedAt: now},
+		{ID: "match", Title: "Gopher", Company: "Acme", Location: "Berlin (Remote)", Salary: "€90,000.00 - €110,000.00", PostedAt: now},
+		{ID: "on_site", Title: "Gopher", Company: "Globex", Location: "Berlin", Salary: "€90,000.00", PostedAt: now},
+		{ID: "low_salary", Title: "Gopher", Company: "Initech", Location: "Remote", Salary: "€40,000.00", PostedAt: now},
+		{ID: "no_salary", Title: "Gopher", Company: "Umbrella", Location: "Remote", PostedAt: now},
+		{ID: "known_company", Title: "Senior Dweeb", Company: "Späti GmbH", Location: "Remote", Salary: "€95,000.00", PostedAt: now},
+	}}
+	n := &recordingNotifier{}
+	j, jCloser := NewConfigurableJobber(l, d, s, WithNotifier(n))
+	defer jCloser()
+
+	if err := j.SetNotification(context.Background(), "golang", "berlin", NotificationPrefs{
+		WebhookURL:       "https://example.com/hook",
+		RemoteOnly:       true,
+		NewCompaniesOnly: true,
+		MinSalary:        80000,
+	}); err != nil {
+		t.Fatalf("failed to set notification: %v", err)
+	}
+
+	t.Run("notifies only the new offers matching the preferences", func(t *testing.T) {
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+		if err != nil {
+			t.Fatalf("unable to retrieve seed query: %v", err)
+		}
+		j.runQuery(q.ID)
+		if len(n.calls) != 1 {
+			t.Fatalf("wanted 1 notification, got %d", len(n.calls))
+		}
+		if n.calls[0].dest != "https://example.com/hook" {
+			t.Errorf("wanted notification sent to the query's webhook, got %s", n.calls[0].dest)
+		}
+		if !slices.Equal(n.calls[0].ids, []string{"match"}) {
+			t.Errorf("wanted notified offers to be [match], got %v", n.calls[0].ids)
+		}
+	})
+
+	t.Run("queries without notification aren't notified", func(t *testing.T) {
+		n.calls = nil
+		q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "data scientist", Location: "new york"})
+		if err != nil {
+			t.Fatalf("unable to retrieve seed query: %v", err)
+		}
+		j.runQuery(q.ID)
+		if len(n.calls) != 0 {
+			t.Errorf("wanted no notifications, got %d", len(n.calls))
+		}
+	})
+
+	t.Run("invalid destinations are rejected", func(t *testing.T) {
+		for _, u := range []string{
+			"", "http://example.com/hook", "https://", "example.com/hook",
+			"https://127.0.0.1/hook", "https://10.0.0.1:8443/hook", "https://[::1]/hook", "https://169.254.169.254/latest",
+			"https://localhost/hook", "https://api.localhost/hook", "https://LOCALHOST./hook",
+		} {
+			err := j.SetNotification(context.Background(), "golang", "berlin", NotificationPrefs{WebhookURL: u})
+			if !errors.Is(err, ErrInvalidNotification) {
+				t.Errorf("wanted ErrInvalidNotification for %q, got %v", u, err)
+			}
+		}
+	})
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	q := &db.Query{Keywords: "golang", Location: "berlin"}
+	offers := []db.CreateOfferParams{{ID: "match", Title: "Gopher", Company: "Acme", Location: "Remote"}}
+
+	tests := []struct {
+		name      string
+		statuses  []int // Responses of the receiver, the last one repeats.
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "delivered", statuses: []int{http.StatusOK}, wantCalls: 1},
+		{name: "server errors are retried", statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent}, wantCalls: 3},
+		{name: "client errors aren't retried", statuses: []int{http.StatusNotFound}, wantCalls: 1, wantErr: true},
+		{name: "retries are bounded", statuses: []int{http.StatusBadGateway}, wantCalls: webhookAttempts, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				calls    int
+				received webhookPayload
+			)
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("unable to decode payload: %v", err)
+				}
+				w.WriteHeader(tt.statuses[min(calls, len(tt.statuses)-1)])
+				calls++
+			}))
+			defer receiver.Close()
+
+			n := newWebhookNotifier()
+			n.backoff = time.Millisecond
+			// The receiver listens on loopback, which the default client refuses.
+			n.client = receiver.Client()
+			err := n.Notify(context.Background(), receiver.URL, q, offers)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("wanted error to be %t, got %v", tt.wantErr, err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("wanted %d calls, got %d", tt.wantCalls, calls)
+			}
+			if received.Keywords != "golang" || len(received.Offers) != 1 || received.Offers[0].ID != "match" {
+				t.Errorf("wanted the query's offers in the payload, got %+v", received)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierPrivateAddress(t *testing.T) {
+	var calls int
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { calls++ }))
+	defer receiver.Close()
+
+	n := newWebhookNotifier()
+	n.backoff = time.Millisecond
+	err := n.Notify(context.Background(), receiver.URL, &db.Query{Keywords: "golang", Location: "berlin"}, nil)
+	if !errors.Is(err, errPrivateAddress) {
+		t.Errorf("wanted errPrivateAddress, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("wanted the loopback receiver not to be called, got %d calls", calls)
+	}
+}
+
+func TestSeenCache(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	tests := []struct {
+		name        string
+		cacheSize   int
+		changed     bool
+		wantUpserts int
+		wantAssocs  int
+	}{
+		{name: "recently seen offers skip the DB", cacheSize: 10, wantUpserts: 1, wantAssocs: 1},
+		{name: "recently seen offers are updated once changed", cacheSize: 10, changed: true, wantUpserts: 2, wantAssocs: 1},
+		{name: "disabled cache stores offers again", cacheSize: 0, wantUpserts: 2, wantAssocs: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, dbCloser := db.NewTestDB(t)
+			defer dbCloser()
+			s := &offersScraper{offers: []db.CreateOfferParams{{
+				ID:       "seen",
+				Source:   scrape.SourceLinkedIn,
+				Title:    "Seen",
+				Company:  "Acme",
+				Location: "Berlin",
+				PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			}}}
+			st := &offerCountingStore{Store: d}
+			j := &Jobber{ctx: context.Background(), logger: l, db: st, scpr: s, scrapes: make(chan struct{}, 1), seen: newSeenCache(tt.cacheSize), circuit: newCircuit(0, 0, 0), offerRetention: defaultOfferRetention}
+			q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+			if err != nil {
+				t.Fatalf("unable to retrieve seed query: %v", err)
+			}
+
+			j.runQuery(q.ID)
+			wantTitle := "Seen"
+			if tt.changed {
+				// The same query scrapes the offer again once its posting changed.
+				wantTitle = "Seen, updated"
+				s.offers[0].Title = wantTitle
+			}
+			j.runQuery(q.ID)
+
+			if st.upserts != tt.wantUpserts {
+				t.Errorf("wanted %d upserts, got %d", tt.wantUpserts, st.upserts)
+			}
+			if st.assocs != tt.wantAssocs {
+				t.Errorf("wanted %d associations, got %d", tt.wantAssocs, st.assocs)
+			}
+			o, err := d.GetOffer(context.Background(), &db.GetOfferParams{Source: scrape.SourceLinkedIn, ID: "seen"})
+			if err != nil {
+				t.Fatalf("unable to retrieve offer: %v", err)
+			}
+			if o.Title != wantTitle {
+				t.Errorf("wanted the offer title to be %q, got %q", wantTitle, o.Title)
+			}
+			count, err := d.CountOffers(context.Background(), q.ID)
+			if err != nil {
+				t.Fatalf("unable to count offers: %v", err)
+			}
+			if count != 1 {
+				t.Errorf("wanted 1 offer in the query's feed, got %d", count)
+			}
+		})
+	}
+
+	t.Run("evicts the least recently used offers", func(t *testing.T) {
+		c := newSeenCache(2)
+		a, b, cc := seenKey{1, offerKey{"linkedin", "a"}}, seenKey{1, offerKey{"linkedin", "b"}}, seenKey{1, offerKey{"linkedin", "c"}}
+		c.add(a, 1)
+		c.add(b, 2)
+		c.get(a) // a is now more recent than b.
+		c.add(cc, 3)
+		_, okA := c.get(a)
+		_, okB := c.get(b)
+		_, okC := c.get(cc)
+		if !okA || okB || !okC {
+			t.Errorf("expected b to be evicted")
+		}
+	})
+}
+
+func TestCanonicalCompany(t *testing.T) {
+	a, err := ParseCompanyAliases(strings.NewReader(`{"Google": ["Google LLC", "Google Inc."], "Späti": ["Späti GmbH"]}`))
+	if err != nil {
+		t.Fatalf("unable to parse company aliases: %v", err)
+	}
+	j := &Jobber{}
+	WithCompanyAliases(a)(j)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Google", want: "Google"},
+		{name: "Google LLC", want: "Google"},
+		{name: "google  inc.", want: "Google"},
+		{name: "Späti GmbH", want: "Späti"},
+		{name: "Acme", want: "Acme"},
+		{name: "Google Cloud", want: "Google Cloud"},
+	}
+	for _, tt := range tests {
+		if got := j.canonicalCompany(tt.name); got != tt.want {
+			t.Errorf("wanted %q to be %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	t.Run("without aliases names pass through", func(t *testing.T) {
+		if got := (&Jobber{}).canonicalCompany("Google LLC"); got != "Google LLC" {
+			t.Errorf("wanted name unchanged, got %q", got)
+		}
+	})
+
+	t.Run("invalid aliases", func(t *testing.T) {
+		if _, err := ParseCompanyAliases(strings.NewReader(`["Google"]`)); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func TestParseSalary(t *testing.T) {
+	tests := map[string]int{
+		"€70,000.00 - €90,000.00": 70000,
+		"$80000 - $110000":        80000,
+		"£45,500/yr":              45500,
+		"":                        0,
+		"competitive":             0,
+	}
+	for s, want := range tests {
+		if got := parseSalary(s); got != want {
+			t.Errorf("wanted salary %q to be %d, got %d", s, want, got)
+		}
+	}
+}
+
+// offersScraper returns the same offers on every scrape.
+// offerCountingStore counts the offers upserted and associated to queries.
+type offerCountingStore struct {
+	Store
+	upserts int
+	assocs  int
+}
+
+func (s *offerCountingStore) UpsertOffer(ctx context.Context, arg *db.UpsertOfferParams) (bool, error) {
+	s.upserts++
+	return s.Store.UpsertOffer(ctx, arg)
+}
+
+func (s *offerCountingStore) CreateQueryOfferAssoc(ctx context.Context, arg *db.CreateQueryOfferAssocParams) error {
+	s.assocs++
+	return s.Store.CreateQueryOfferAssoc(ctx, arg)
+}
+
+type offersScraper struct {
+	offers []db.CreateOfferParams
+}
+
+func (s *offersScraper) Scrape(context.Context, *db.Query) ([]db.CreateOfferParams, error) {
+	return s.offers, nil
+}
+
+func (s *offersScraper) Name() string { return "mock" }
+
+type notification struct {
+	dest string
+	ids  []string
+}
+
+// recordingNotifier records the notifications instead of sending them.
+type recordingNotifier struct {
+	calls []notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, dest string, _ *db.Query, offers []db.CreateOfferParams) error {
+	var ids []string
+	for _, o := range offers {
+		ids = append(ids, o.ID)
+	}
+	n.calls = append(n.calls, notification{dest: dest, ids: ids})
+	return nil
 }
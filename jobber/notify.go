@@ -0,0 +1,281 @@
+package jobber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/alwedo/jobber/db"
+)
+
+const (
+	notifyTimeout = 10 * time.Second
+	// Webhooks are retried with exponential backoff on network errors,
+	// server errors and rate limits. Delivery remains best-effort.
+	webhookAttempts = 3
+	webhookBackoff  = time.Second
+)
+
+var ErrInvalidNotification = errors.New("invalid notification")
+
+// errPrivateAddress is returned by the webhooks resolving to non public
+// addresses, so they can't reach the server's network.
+var errPrivateAddress = errors.New("webhook address isn't public")
+
+// Notifier sends the new offers of a query to its notification destination.
+type Notifier interface {
+	Notify(ctx context.Context, dest string, q *db.Query, offers []db.CreateOfferParams) error
+}
+
+// WithNotifier replaces the default webhook notifier.
+func WithNotifier(n Notifier) Option {
+	return func(j *Jobber) {
+		j.notifier = n
+	}
+}
+
+// NotificationPrefs are a query's notification destination and
+// the preferences deciding which of its new offers are notified.
+type NotificationPrefs struct {
+	WebhookURL       string
+	RemoteOnly       bool // Only offers whose location is remote.
+	NewCompaniesOnly bool // Only offers from companies the query didn't have yet.
+	MinSalary        int  // Only offers with a salary of at least this amount.
+}
+
+func (p *NotificationPrefs) validate() error {
+	u, err := url.Parse(p.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("%w: webhook url: %w", ErrInvalidNotification, err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%w: webhook url must be an absolute https url", ErrInvalidNotification)
+	}
+	// The resolved addresses are checked when posting, the
+	// hosts that obviously aren't public are rejected early.
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if _, err := netip.ParseAddr(host); err == nil || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: webhook url must have a public hostname", ErrInvalidNotification)
+	}
+	if p.MinSalary < 0 {
+		return fmt.Errorf("%w: min salary can't be negative", ErrInvalidNotification)
+	}
+	return nil
+}
+
+// SetNotification opts a query into new offers notifications, replacing its previous preferences.
+// If the query doesn't exist, ErrQueryNotFound will be returned.
+func (j *Jobber) SetNotification(ctx context.Context, keywords, location string, p NotificationPrefs) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	keywords, location = canonicalize(keywords, location)
+	q, err := j.db.GetQuery(ctx, &db.GetQueryParams{
+		Keywords: keywords,
+		Location: location,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get query: %w", notFound(err, ErrQueryNotFound))
+	}
+	if err := j.db.UpsertQueryNotification(ctx, &db.UpsertQueryNotificationParams{
+		QueryID:          q.ID,
+		WebhookUrl:       p.WebhookURL,
+		RemoteOnly:       p.RemoteOnly,
+		NewCompaniesOnly: p.NewCompaniesOnly,
+		MinSalary:        int32(min(p.MinSalary, 1<<31-1)), //nolint: gosec
+	}); err != nil {
+		return fmt.Errorf("failed to store notification: %w", err)
+	}
+	return nil
+}
+
+// offerKey identifies an offer, as IDs are only unique within their source.
+type offerKey struct {
+	source, id string
+}
+
+// offersToNotify returns the query's notification and the scraped offers matching its
+// preferences that the query didn't have yet. It must run before the offers are stored.
+func (j *Jobber) offersToNotify(q *db.Query, offers []db.CreateOfferParams) (*db.QueryNotification, []db.CreateOfferParams, error) {
+	n, err := j.db.GetQueryNotification(j.ctx, q.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	known, err := j.db.ListOffers(j.ctx, &db.ListOffersParams{ID: q.ID})
+	if err != nil {
+		return nil, nil, err
+	}
+	knownIDs := make(map[offerKey]bool, len(known))
+	knownCompanies := make(map[string]bool, len(known))
+	for _, o := range known {
+		knownIDs[offerKey{o.Source, o.ID}] = true
+		knownCompanies[strings.ToLower(o.Company)] = true
+	}
+
+	var notify []db.CreateOfferParams
+	for _, o := range offers {
+		if knownIDs[offerKey{o.Source, o.ID}] || !matchesPrefs(n, &o, knownCompanies) {
+			continue
+		}
+		notify = append(notify, o)
+	}
+	return n, notify, nil
+}
+
+func matchesPrefs(n *db.QueryNotification, o *db.CreateOfferParams, knownCompanies map[string]bool) bool {
+	if n.RemoteOnly && !strings.Contains(strings.ToLower(o.Location), "remote") {
+		return false
+	}
+	if n.NewCompaniesOnly && knownCompanies[strings.ToLower(o.Company)] {
+		return false
+	}
+	if n.MinSalary > 0 && parseSalary(o.Salary) < int(n.MinSalary) {
+		return false
+	}
+	return true
+}
+
+// parseSalary returns the lower amount of a salary snippet, ie. "€70,000.00 - €90,000.00"
+// returns 70000. Snippets without amounts return 0.
+func parseSalary(s string) int {
+	start := strings.IndexFunc(s, unicode.IsDigit)
+	if start == -1 {
+		return 0
+	}
+	var digits strings.Builder
+	for _, r := range s[start:] {
+		if unicode.IsDigit(r) {
+			digits.WriteRune(r)
+			continue
+		}
+		if r != ',' {
+			break // Decimals, ranges and currencies end the amount.
+		}
+	}
+	n, err := strconv.Atoi(digits.String())
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+type webhookNotifier struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+func newWebhookNotifier() *webhookNotifier {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be the one dialed, hiding the webhook's address.
+	t.Proxy = nil
+	t.DialContext = (&net.Dialer{Timeout: notifyTimeout, Control: publicOnly}).DialContext
+	return &webhookNotifier{
+		client:   &http.Client{Timeout: notifyTimeout, Transport: t},
+		attempts: webhookAttempts,
+		backoff:  webhookBackoff,
+	}
+}
+
+type webhookPayload struct {
+	Keywords string         `json:"keywords"`
+	Location string         `json:"location"`
+	Offers   []webhookOffer `json:"offers"`
+}
+
+type webhookOffer struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Company  string    `json:"company"`
+	Location string    `json:"location"`
+	Salary   string    `json:"salary,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// Notify posts the offers as JSON to the webhook url, retrying failed deliveries.
+func (w *webhookNotifier) Notify(ctx context.Context, dest string, q *db.Query, offers []db.CreateOfferParams) error {
+	p := webhookPayload{Keywords: q.Keywords, Location: q.Location}
+	for _, o := range offers {
+		p.Offers = append(p.Offers, webhookOffer{
+			ID:       o.ID,
+			Title:    o.Title,
+			Company:  o.Company,
+			Location: o.Location,
+			Salary:   o.Salary,
+			URL:      o.Url,
+			PostedAt: o.PostedAt.Time,
+		})
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	backoff := w.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(ctx, dest, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.attempts {
+			return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends the payload once, reporting whether failures are worth retrying.
+func (w *webhookNotifier) post(ctx context.Context, dest string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dest, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		retry := ctx.Err() == nil && !errors.Is(err, errPrivateAddress)
+		return retry, fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("received status code: %d, url: %s", resp.StatusCode, dest)
+	}
+	return false, nil
+}
+
+// publicOnly refuses to connect to loopback, private, link-local and other non
+// public addresses. It runs once the host is resolved, so it also covers the
+// hostnames pointing to them and the redirects.
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", errPrivateAddress, ip)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package jobber
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+
+	"github.com/alwedo/jobber/db"
+	"github.com/alwedo/jobber/metrics"
+)
+
+const defaultSeenCacheSize = 10000
+
+// WithSeenCacheSize sets how many recently stored offers are remembered
+// per query, so they're not stored again, unless changed, nor notified again
+// on the next runs.
+// Defaults to 10000, 0 disables the cache.
+func WithSeenCacheSize(n int) Option {
+	return func(j *Jobber) {
+		j.seen = newSeenCache(n)
+	}
+}
+
+type seenKey struct {
+	queryID int64
+	offer   offerKey
+}
+
+type seenEntry struct {
+	key  seenKey
+	hash uint64
+}
+
+// offerHash hashes the offer's fields which can change between scrapes,
+// to tell whether a seen offer needs to be stored again.
+func offerHash(o *db.CreateOfferParams) uint64 {
+	h := fnv.New64a()
+	for _, f := range []string{o.Title, o.RawCompany, o.Company, o.Location, o.Salary, o.Description, o.Url, o.LogoUrl} {
+		h.Write([]byte(f)) //nolint: errcheck
+		h.Write([]byte{0}) //nolint: errcheck
+	}
+	return h.Sum64()
+}
+
+// seenCache is a size bounded LRU of the offers already stored and
+// associated to a query, along with the hash of their stored fields. It only saves DB round-trips for offers scraped
+// over and over, the DB remains the source of truth.
+type seenCache struct {
+	max int
+
+	mu    sync.Mutex
+	items map[seenKey]*list.Element
+	lru   *list.List
+}
+
+func newSeenCache(size int) *seenCache {
+	return &seenCache{
+		max:   size,
+		items: make(map[seenKey]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// get returns the hash of the offer if it was recently seen, marking it as used.
+func (c *seenCache) get(k seenKey) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[k]
+	if !ok {
+		return 0, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*seenEntry).hash, true
+}
+
+// add remembers the offer and its hash, evicting the least recently used ones.
+func (c *seenCache) add(k seenKey, hash uint64) {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[k]; ok {
+		e.Value.(*seenEntry).hash = hash
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[k] = c.lru.PushFront(&seenEntry{key: k, hash: hash})
+	for c.lru.Len() > c.max {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.items, e.Value.(*seenEntry).key)
+	}
+	metrics.CacheItems.WithLabelValues("seen").Set(float64(c.lru.Len()))
+}
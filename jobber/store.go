@@ -0,0 +1,38 @@
+package jobber
+
+import (
+	"context"
+
+	"github.com/alwedo/jobber/db"
+)
+
+// Store is the DB access the jobber needs, implemented by db.Queries.
+// Tests can replace it with an in-memory one to run without Postgres.
+type Store interface {
+	CountOffers(ctx context.Context, queryID int64) (int64, error)
+	CountOffersByCompany(ctx context.Context, queryID int64) ([]*db.CountOffersByCompanyRow, error)
+	CountOffersPerQuery(ctx context.Context) ([]int64, error)
+	CreateQuery(ctx context.Context, arg *db.CreateQueryParams) (*db.Query, error)
+	CreateQueryGroup(ctx context.Context, memberIds string) (*db.QueryGroup, error)
+	CreateQueryGroupMember(ctx context.Context, arg *db.CreateQueryGroupMemberParams) error
+	CreateQueryOfferAssoc(ctx context.Context, arg *db.CreateQueryOfferAssocParams) error
+	DeleteOldOffers(ctx context.Context, arg *db.DeleteOldOffersParams) error
+	DeleteQuery(ctx context.Context, id int64) error
+	DeleteQueryGroupsByQuery(ctx context.Context, queryID int64) error
+	GetOffer(ctx context.Context, arg *db.GetOfferParams) (*db.Offer, error)
+	GetQuery(ctx context.Context, arg *db.GetQueryParams) (*db.Query, error)
+	GetQueryByID(ctx context.Context, id int64) (*db.Query, error)
+	GetQueryNotification(ctx context.Context, queryID int64) (*db.QueryNotification, error)
+	ListOffers(ctx context.Context, arg *db.ListOffersParams) ([]*db.Offer, error)
+	ListPopularQueries(ctx context.Context, limit int32) ([]*db.Query, error)
+	ListQueries(ctx context.Context) ([]*db.Query, error)
+	ListQueryGroupMembers(ctx context.Context, groupID int64) ([]*db.Query, error)
+	SearchOffers(ctx context.Context, arg *db.SearchOffersParams) ([]*db.Offer, error)
+	UpdateQueryError(ctx context.Context, arg *db.UpdateQueryErrorParams) error
+	UpdateQueryQATIfStale(ctx context.Context, arg *db.UpdateQueryQATIfStaleParams) (int64, error)
+	UpdateQueryUAT(ctx context.Context, id int64) error
+	UpsertOffer(ctx context.Context, arg *db.UpsertOfferParams) (bool, error)
+	UpsertQueryNotification(ctx context.Context, arg *db.UpsertQueryNotificationParams) error
+}
+
+var _ Store = (*db.Queries)(nil)
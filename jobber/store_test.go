@@ -0,0 +1,452 @@
+package jobber
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alwedo/jobber/db"
+	"github.com/alwedo/jobber/scrape"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TestFakeStore runs a query's lifecycle against the in-memory
+// store, so it doesn't need Postgres like the other tests.
+func TestFakeStore(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	s := newFakeStore()
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	scpr := &offersScraper{offers: []db.CreateOfferParams{
+		{ID: "1", Title: "Go Developer", Company: "Cuak", Source: scrape.SourceLinkedIn, PostedAt: now},
+		{ID: "2", Title: "Gopher", Company: "Squeek", Source: scrape.SourceLinkedIn, PostedAt: now},
+	}}
+	j, jCloser := NewConfigurableJobber(l, s, scpr)
+	defer jCloser()
+	ctx := context.Background()
+
+	done, err := j.CreateQuery(ctx, " GoLang ", "Berlin,", QueryOptions{})
+	if err != nil {
+		t.Fatalf("failed to create query: %v", err)
+	}
+	if !done {
+		t.Errorf("expected the initial scrape to complete")
+	}
+	if _, err := j.CreateQuery(ctx, "golang", "berlin", QueryOptions{}); !errors.Is(err, ErrQueryExists) {
+		t.Errorf("expected ErrQueryExists, got %v", err)
+	}
+
+	offers, err := j.ListOffers(ctx, "golang", "berlin", 0, 0)
+	if err != nil {
+		t.Fatalf("failed to list offers: %v", err)
+	}
+	if len(offers) != len(scpr.offers) {
+		t.Errorf("expected %d offers, got %d", len(scpr.offers), len(offers))
+	}
+	if n, err := j.CountOffers(ctx, "golang", "berlin"); err != nil || n != int64(len(scpr.offers)) {
+		t.Errorf("expected %d offers counted, got %d (error: %v)", len(scpr.offers), n, err)
+	}
+	if _, err := j.GetOffer(ctx, scrape.SourceLinkedIn, "2"); err != nil {
+		t.Errorf("failed to get offer: %v", err)
+	}
+
+	if err := j.DeleteQuery(ctx, "golang", "berlin"); err != nil {
+		t.Fatalf("failed to delete query: %v", err)
+	}
+	if _, err := j.ListOffers(ctx, "golang", "berlin", 0, 0); !errors.Is(err, ErrQueryNotFound) {
+		t.Errorf("expected ErrQueryNotFound, got %v", err)
+	}
+	for _, jb := range j.sched.Jobs() {
+		if slices.Contains(jb.Tags(), queryKey{"golang", "berlin"}.tag()) {
+			t.Errorf("expected deleted query job to be unscheduled")
+		}
+	}
+}
+
+type fakeOfferKey struct{ source, id string }
+
+// fakeStore is an in-memory Store, keeping just enough of the
+// queries' behaviour for the jobber's unit tests.
+type fakeStore struct {
+	mu            sync.Mutex
+	lastID        int64
+	queries       map[int64]*db.Query
+	offers        map[fakeOfferKey]*db.Offer
+	queryOffers   map[int64][]fakeOfferKey
+	notifications map[int64]*db.QueryNotification
+	groups        map[int64]*db.QueryGroup
+	groupMembers  map[int64][]int64
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		queries:       make(map[int64]*db.Query),
+		offers:        make(map[fakeOfferKey]*db.Offer),
+		queryOffers:   make(map[int64][]fakeOfferKey),
+		notifications: make(map[int64]*db.QueryNotification),
+		groups:        make(map[int64]*db.QueryGroup),
+		groupMembers:  make(map[int64][]int64),
+	}
+}
+
+func fakeNow() pgtype.Timestamptz { return pgtype.Timestamptz{Time: time.Now(), Valid: true} }
+
+// queryOffersLocked returns the query's offers, the most recently posted first.
+func (s *fakeStore) queryOffersLocked(queryID int64) []*db.Offer {
+	var offers []*db.Offer
+	for _, k := range s.queryOffers[queryID] {
+		o := *s.offers[k]
+		offers = append(offers, &o)
+	}
+	slices.SortFunc(offers, func(a, b *db.Offer) int {
+		return cmp.Or(b.PostedAt.Time.Compare(a.PostedAt.Time), cmp.Compare(a.Source, b.Source), cmp.Compare(a.ID, b.ID))
+	})
+	return offers
+}
+
+func (s *fakeStore) sortedQueriesLocked() []*db.Query {
+	var queries []*db.Query
+	for _, q := range s.queries {
+		c := *q
+		queries = append(queries, &c)
+	}
+	slices.SortFunc(queries, func(a, b *db.Query) int { return cmp.Compare(a.ID, b.ID) })
+	return queries
+}
+
+func (s *fakeStore) CountOffers(_ context.Context, queryID int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.queryOffers[queryID])), nil
+}
+
+func (s *fakeStore) CountOffersByCompany(_ context.Context, queryID int64) ([]*db.CountOffersByCompanyRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]int64)
+	for _, o := range s.queryOffersLocked(queryID) {
+		counts[o.Company]++
+	}
+	var rows []*db.CountOffersByCompanyRow
+	for c, n := range counts {
+		rows = append(rows, &db.CountOffersByCompanyRow{Company: c, OfferCount: n})
+	}
+	slices.SortFunc(rows, func(a, b *db.CountOffersByCompanyRow) int {
+		return cmp.Or(cmp.Compare(b.OfferCount, a.OfferCount), cmp.Compare(a.Company, b.Company))
+	})
+	return rows, nil
+}
+
+func (s *fakeStore) CountOffersPerQuery(context.Context) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var counts []int64
+	for _, q := range s.sortedQueriesLocked() {
+		counts = append(counts, int64(len(s.queryOffers[q.ID])))
+	}
+	return counts, nil
+}
+
+func (s *fakeStore) CreateQuery(_ context.Context, arg *db.CreateQueryParams) (*db.Query, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range s.queries {
+		if q.Keywords == arg.Keywords && q.Location == arg.Location {
+			return nil, &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+		}
+	}
+	s.lastID++
+	q := &db.Query{
+		ID:              s.lastID,
+		Keywords:        arg.Keywords,
+		Location:        arg.Location,
+		CreatedAt:       fakeNow(),
+		QueriedAt:       fakeNow(),
+		UpdatedAt:       fakeNow(),
+		IntervalHours:   arg.IntervalHours,
+		TimePostedRange: arg.TimePostedRange,
+		Remote:          arg.Remote,
+		GeoID:           arg.GeoID,
+	}
+	s.queries[q.ID] = q
+	c := *q
+	return &c, nil
+}
+
+func (s *fakeStore) CreateQueryGroup(_ context.Context, memberIds string) (*db.QueryGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.groups {
+		if g.MemberIds == memberIds {
+			c := *g
+			return &c, nil
+		}
+	}
+	s.lastID++
+	g := &db.QueryGroup{ID: s.lastID, MemberIds: memberIds, CreatedAt: fakeNow()}
+	s.groups[g.ID] = g
+	c := *g
+	return &c, nil
+}
+
+func (s *fakeStore) CreateQueryGroupMember(_ context.Context, arg *db.CreateQueryGroupMemberParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[arg.GroupID]; !ok {
+		return &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation}
+	}
+	if _, ok := s.queries[arg.QueryID]; !ok {
+		return &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation}
+	}
+	if !slices.Contains(s.groupMembers[arg.GroupID], arg.QueryID) {
+		s.groupMembers[arg.GroupID] = append(s.groupMembers[arg.GroupID], arg.QueryID)
+	}
+	return nil
+}
+
+func (s *fakeStore) CreateQueryOfferAssoc(_ context.Context, arg *db.CreateQueryOfferAssocParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := fakeOfferKey{arg.OfferSource, arg.OfferID}
+	if _, ok := s.queries[arg.QueryID]; !ok {
+		return &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation}
+	}
+	if _, ok := s.offers[k]; !ok {
+		return &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation}
+	}
+	if !slices.Contains(s.queryOffers[arg.QueryID], k) {
+		s.queryOffers[arg.QueryID] = append(s.queryOffers[arg.QueryID], k)
+	}
+	return nil
+}
+
+func (s *fakeStore) DeleteOldOffers(_ context.Context, arg *db.DeleteOldOffersParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, o := range s.offers {
+		retention := arg.DefaultRetention
+		if i := slices.Index(arg.Sources, o.Source); i >= 0 && i < len(arg.Retentions) {
+			retention = arg.Retentions[i]
+		}
+		if time.Since(o.PostedAt.Time).Seconds() <= retention {
+			continue
+		}
+		delete(s.offers, k)
+		for id, keys := range s.queryOffers {
+			s.queryOffers[id] = slices.DeleteFunc(keys, func(qk fakeOfferKey) bool { return qk == k })
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) DeleteQuery(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.queries, id)
+	delete(s.queryOffers, id)
+	delete(s.notifications, id)
+	return nil
+}
+
+func (s *fakeStore) DeleteQueryGroupsByQuery(_ context.Context, queryID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, members := range s.groupMembers {
+		if slices.Contains(members, queryID) {
+			delete(s.groups, id)
+			delete(s.groupMembers, id)
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) GetOffer(_ context.Context, arg *db.GetOfferParams) (*db.Offer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.offers[fakeOfferKey{arg.Source, arg.ID}]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c := *o
+	return &c, nil
+}
+
+func (s *fakeStore) GetQuery(_ context.Context, arg *db.GetQueryParams) (*db.Query, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range s.queries {
+		if q.Keywords == arg.Keywords && q.Location == arg.Location {
+			c := *q
+			return &c, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *fakeStore) GetQueryByID(_ context.Context, id int64) (*db.Query, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c := *q
+	return &c, nil
+}
+
+func (s *fakeStore) GetQueryNotification(_ context.Context, queryID int64) (*db.QueryNotification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, ok := s.notifications[queryID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c := *n
+	return &c, nil
+}
+
+func (s *fakeStore) ListOffers(_ context.Context, arg *db.ListOffersParams) ([]*db.Offer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offers := s.queryOffersLocked(arg.ID)
+	offers = offers[min(int(arg.Offset), len(offers)):]
+	if arg.Limit > 0 {
+		offers = offers[:min(int(arg.Limit), len(offers))]
+	}
+	return offers, nil
+}
+
+func (s *fakeStore) ListPopularQueries(_ context.Context, limit int32) ([]*db.Query, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queries := slices.DeleteFunc(s.sortedQueriesLocked(), func(q *db.Query) bool {
+		return time.Since(q.QueriedAt.Time) > 7*24*time.Hour
+	})
+	slices.SortStableFunc(queries, func(a, b *db.Query) int { return b.QueriedAt.Time.Compare(a.QueriedAt.Time) })
+	return queries[:min(int(limit), len(queries))], nil
+}
+
+func (s *fakeStore) ListQueries(context.Context) ([]*db.Query, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedQueriesLocked(), nil
+}
+
+func (s *fakeStore) ListQueryGroupMembers(_ context.Context, groupID int64) ([]*db.Query, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var queries []*db.Query
+	for _, id := range s.groupMembers[groupID] {
+		if q, ok := s.queries[id]; ok {
+			c := *q
+			queries = append(queries, &c)
+		}
+	}
+	slices.SortFunc(queries, func(a, b *db.Query) int { return cmp.Compare(a.ID, b.ID) })
+	return queries, nil
+}
+
+// SearchOffers matches the words as substrings, unlike Postgres' full text search.
+func (s *fakeStore) SearchOffers(_ context.Context, arg *db.SearchOffersParams) ([]*db.Offer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	words := strings.Fields(strings.ToLower(arg.Query))
+	var offers []*db.Offer
+	for _, o := range s.offers {
+		text := strings.ToLower(o.Title + " " + o.Company + " " + o.Location)
+		if len(words) > 0 && !slices.ContainsFunc(words, func(w string) bool { return !strings.Contains(text, w) }) {
+			c := *o
+			offers = append(offers, &c)
+		}
+	}
+	slices.SortFunc(offers, func(a, b *db.Offer) int { return b.PostedAt.Time.Compare(a.PostedAt.Time) })
+	return offers[:min(int(arg.Limit), len(offers))], nil
+}
+
+func (s *fakeStore) UpdateQueryError(_ context.Context, arg *db.UpdateQueryErrorParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.queries[arg.ID]; ok {
+		q.LastError = arg.LastError
+		q.LastErrorAt = fakeNow()
+	}
+	return nil
+}
+
+func (s *fakeStore) UpdateQueryQATIfStale(_ context.Context, arg *db.UpdateQueryQATIfStaleParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.queries[arg.ID]
+	if !ok || time.Since(q.QueriedAt.Time).Seconds() <= arg.StaleAfter {
+		return 0, nil
+	}
+	q.QueriedAt = fakeNow()
+	return 1, nil
+}
+
+func (s *fakeStore) UpdateQueryUAT(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.queries[id]; ok {
+		q.UpdatedAt = fakeNow()
+		q.LastError = ""
+		q.LastErrorAt = pgtype.Timestamptz{}
+	}
+	return nil
+}
+
+func (s *fakeStore) UpsertOffer(_ context.Context, arg *db.UpsertOfferParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := fakeOfferKey{arg.Source, arg.ID}
+	o := &db.Offer{
+		ID:          arg.ID,
+		Title:       arg.Title,
+		Company:     arg.Company,
+		Location:    arg.Location,
+		PostedAt:    arg.PostedAt,
+		CreatedAt:   fakeNow(),
+		LogoUrl:     arg.LogoUrl,
+		Salary:      arg.Salary,
+		Url:         arg.Url,
+		Source:      arg.Source,
+		RawCompany:  arg.RawCompany,
+		Description: arg.Description,
+	}
+	existing, ok := s.offers[k]
+	if ok {
+		o.CreatedAt = existing.CreatedAt
+		if o.Description == "" {
+			o.Description = existing.Description
+		}
+	}
+	s.offers[k] = o
+	return !ok, nil
+}
+
+func (s *fakeStore) UpsertQueryNotification(_ context.Context, arg *db.UpsertQueryNotificationParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.queries[arg.QueryID]; !ok {
+		return &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation}
+	}
+	s.notifications[arg.QueryID] = &db.QueryNotification{
+		QueryID:          arg.QueryID,
+		WebhookUrl:       arg.WebhookUrl,
+		RemoteOnly:       arg.RemoteOnly,
+		NewCompaniesOnly: arg.NewCompaniesOnly,
+		MinSalary:        arg.MinSalary,
+	}
+	return nil
+}
+
+var _ Store = (*fakeStore)(nil)
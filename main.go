@@ -2,25 +2,65 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/alwedo/jobber/db"
 	"github.com/alwedo/jobber/jobber"
 	"github.com/alwedo/jobber/metrics"
+	"github.com/alwedo/jobber/scrape"
 	"github.com/alwedo/jobber/server"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "golang.org/x/crypto/x509roots/fallback" // CA bundle for FROM Scratch
 )
 
+const (
+	defaultDBConnectAttempts = 5
+	dbConnectBackoff         = time.Second // Initial wait between DB connection attempts.
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "scrape" {
+		// The offers are printed to stdout, so the logs go to stderr.
+		log := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		if err := runScrape(log, os.Args[2:]); err != nil {
+			log.Error("unable to scrape", slog.Any("error", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	log := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(log, os.Args[2:]); err != nil {
+			log.Error("unable to migrate", slog.Any("error", err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// run returns after its deferred closers ran, so
+	// exiting here doesn't leak the DB nor the jobber.
+	if err := run(log); err != nil {
+		log.Error("shutting down", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(log *slog.Logger) error {
 	var (
-		log    = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 		ctx    = context.Background()
 		svrErr = make(chan error)
 		c      = make(chan os.Signal, 1)
@@ -28,16 +68,92 @@ func main() {
 
 	metrics.Init() // will panic if fails to init.
 
-	d, dbCloser := initDB(ctx, log)
-	defer dbCloser()
+	pool, err := initDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	d := db.New(pool)
+
+	scrapeOpts, err := scrapeOptions(log, os.Getenv)
+	if err != nil {
+		return err
+	}
+	scpr, err := scrape.LinkedIn(scrapeOpts...)
+	if err != nil {
+		return fmt.Errorf("unable to create scraper: %w", err)
+	}
+
+	var jobberOpts []jobber.Option
+	if p := os.Getenv("COMPANY_ALIASES"); p != "" {
+		a, err := readCompanyAliases(p)
+		if err != nil {
+			return err
+		}
+		jobberOpts = append(jobberOpts, jobber.WithCompanyAliases(a))
+	}
+
+	if v := os.Getenv("CLEANUP_CRON"); v != "" {
+		jobberOpts = append(jobberOpts, jobber.WithCleanupCron(v))
+	}
+	if v := os.Getenv("OFFER_RETENTION"); v != "" {
+		r, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid OFFER_RETENTION: %w", err)
+		}
+		jobberOpts = append(jobberOpts, jobber.WithOfferRetention(r))
+	}
 
-	j, jCloser := jobber.New(log, d)
+	j, jCloser := jobber.NewConfigurableJobber(log, d, scpr, jobberOpts...)
 	defer jCloser()
 
-	svr, err := server.New(log, j)
+	var opts []server.Option
+	if os.Getenv("LOGO_PROXY") == "true" {
+		opts = append(opts, server.WithLogoProxy())
+	}
+	if u := os.Getenv("RSS_IMAGE_URL"); u != "" {
+		opts = append(opts, server.WithImageURL(u))
+	}
+	opts = append(opts, server.WithPing(pool.Ping))
+	if v := os.Getenv("RATE_LIMIT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid RATE_LIMIT: %w", err)
+		}
+		opts = append(opts, server.WithRateLimit(n))
+	}
+	if v := os.Getenv("POPULAR_FEEDS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid POPULAR_FEEDS: %w", err)
+		}
+		opts = append(opts, server.WithPopularFeeds(n))
+	}
+	if v := os.Getenv("API_KEY"); v != "" {
+		opts = append(opts, server.WithAPIKey(v))
+	}
+	if v := os.Getenv("ADMIN_SECRET"); v != "" {
+		opts = append(opts, server.WithAdminSecret(v))
+	}
+	if os.Getenv("FEED_SOURCE_URL") == "true" {
+		opts = append(opts, server.WithSourceURL())
+	}
+	if v := os.Getenv("FEED_CACHE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid FEED_CACHE_TTL: %w", err)
+		}
+		opts = append(opts, server.WithFeedCacheTTL(d))
+	}
+	timeouts, err := httpTimeouts(os.Getenv)
 	if err != nil {
-		log.Error("unable to create server", slog.Any("error", err))
-		return
+		return err
+	}
+	opts = append(opts, server.WithTimeouts(timeouts))
+
+	svr, err := server.New(log, j, opts...)
+	if err != nil {
+		return fmt.Errorf("unable to create server: %w", err)
 	}
 	defer func() {
 		if err := svr.Shutdown(ctx); err != nil {
@@ -60,25 +176,310 @@ func main() {
 
 	select {
 	case e := <-svrErr:
-		log.Error("server error, shutting down...", slog.Any("error", e))
+		return fmt.Errorf("server error: %w", e)
 	case <-c:
 		log.Info("shutting down...")
 	}
+	return nil
 }
 
-func initDB(ctx context.Context, log *slog.Logger) (*db.Queries, func()) {
-	host := os.Getenv("DB_HOST")
+// runMigrate runs the migrate subcommand against the configured DB, either
+// `jobber migrate up` applying the pending migrations, or `jobber migrate
+// down [n]` reverting the last n applied ones, 1 when missing.
+func runMigrate(log *slog.Logger, args []string) error {
+	up, steps, err := migrateArgs(args)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pool, err := initDB(ctx, log)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	if up {
+		n, err := db.MigrateUp(ctx, pool)
+		if err != nil {
+			return err
+		}
+		log.Info("applied migrations", slog.Int("count", n))
+		return nil
+	}
+	n, err := db.MigrateDown(ctx, pool, steps)
+	if err != nil {
+		return err
+	}
+	log.Info("reverted migrations", slog.Int("count", n))
+	return nil
+}
+
+// migrateArgs parses the migrate subcommand's direction and down steps.
+func migrateArgs(args []string) (up bool, steps int, err error) {
+	switch {
+	case len(args) == 1 && args[0] == "up":
+		return true, 0, nil
+	case len(args) == 1 && args[0] == "down":
+		return false, 1, nil
+	case len(args) == 2 && args[0] == "down":
+		steps, err := strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			return false, 0, fmt.Errorf("invalid migrate down steps: %s", args[1])
+		}
+		return false, steps, nil
+	}
+	return false, 0, fmt.Errorf("invalid migrate args %q, expected up or down [n]", args)
+}
+
+// runScrape runs the scrape subcommand, ie. `jobber scrape --keywords=golang
+// --location=berlin`, scraping LinkedIn once without the DB nor the server.
+func runScrape(log *slog.Logger, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	scrapeOpts, err := scrapeOptions(log, os.Getenv)
+	if err != nil {
+		return err
+	}
+	scpr, err := scrape.LinkedIn(scrapeOpts...)
+	if err != nil {
+		return fmt.Errorf("unable to create scraper: %w", err)
+	}
+	return scrapeOnce(ctx, scpr, args, os.Stdout)
+}
+
+// scrapeOnce parses the scrape subcommand's flags, runs the query's
+// scrape once and writes the offers found to out as JSON.
+func scrapeOnce(ctx context.Context, scpr scrape.Scraper, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	keywords := fs.String("keywords", "", "keywords to search, ie. golang")
+	location := fs.String("location", "", "location to search, optional for remote searches")
+	remote := fs.Bool("remote", false, "only search remote offers")
+	since := fs.String("since", scrape.TimePostedWeek, "time posted range of the offers: 24h, week, month or all")
+	geoID := fs.String("geo-id", "", "LinkedIn's ID of the location")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	q := &db.Query{
+		Keywords:        strings.TrimSpace(*keywords),
+		Location:        strings.TrimSpace(*location),
+		Remote:          *remote,
+		TimePostedRange: *since,
+		GeoID:           strings.TrimSpace(*geoID),
+	}
+	if q.Keywords == "" || (q.Location == "" && !q.Remote) {
+		return errors.New("missing flags: --keywords and --location are required, --location is optional with --remote")
+	}
+	if !scrape.ValidTimePostedRange(q.TimePostedRange) {
+		return fmt.Errorf("invalid --since: %s", q.TimePostedRange)
+	}
+	offers, err := scpr.Scrape(ctx, q)
+	if err != nil {
+		return fmt.Errorf("unable to scrape %s: %w", scpr.Name(), err)
+	}
+	if offers == nil {
+		offers = []db.CreateOfferParams{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(offers)
+}
+
+// scrapeOptions reads the LinkedIn scraper's options from the environment.
+func scrapeOptions(log *slog.Logger, getenv func(string) string) ([]scrape.LinkedInOption, error) {
+	scrapeOpts := []scrape.LinkedInOption{scrape.WithLogger(log)}
+	if p := getenv("SCRAPE_PROXY"); p != "" {
+		scrapeOpts = append(scrapeOpts, scrape.WithProxy(p))
+	}
+	if getenv("SCRAPE_DISABLE_HTTP2") == "true" {
+		scrapeOpts = append(scrapeOpts, scrape.WithoutHTTP2())
+	}
+	if getenv("SCRAPE_DESCRIPTIONS") == "true" {
+		scrapeOpts = append(scrapeOpts, scrape.WithDescriptions(true))
+	}
+	if v := getenv("SCRAPE_RETRY_BUDGET"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCRAPE_RETRY_BUDGET: %w", err)
+		}
+		scrapeOpts = append(scrapeOpts, scrape.WithRetryBudget(n))
+	}
+	if v := getenv("SCRAPE_PAGE_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCRAPE_PAGE_DELAY: %w", err)
+		}
+		scrapeOpts = append(scrapeOpts, scrape.WithPageDelay(d))
+	}
+	if v := getenv("SCRAPE_MIN_TITLE_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCRAPE_MIN_TITLE_LENGTH: %w", err)
+		}
+		scrapeOpts = append(scrapeOpts, scrape.WithMinTitleLength(n))
+	}
+	return scrapeOpts, nil
+}
+
+// readCompanyAliases reads the company aliases JSON file at path.
+func readCompanyAliases(path string) (jobber.CompanyAliases, error) {
+	f, err := os.Open(path) //nolint: gosec
+	if err != nil {
+		return nil, fmt.Errorf("unable to open company aliases: %w", err)
+	}
+	defer f.Close()
+	return jobber.ParseCompanyAliases(f)
+}
+
+func initDB(ctx context.Context, log *slog.Logger) (*pgxpool.Pool, error) {
+	attempts := defaultDBConnectAttempts
+	if v := os.Getenv("DB_CONNECT_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid DB_CONNECT_ATTEMPTS: %s", v)
+		}
+		attempts = n
+	}
+	connStr, err := connString(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := poolConfig(connStr, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := connect(ctx, log, attempts, dbConnectBackoff, func(ctx context.Context) (*pgxpool.Pool, error) {
+		pool, err := pgxpool.NewWithConfig(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to initialize db connection: %w", err)
+		}
+		if err := pool.Ping(ctx); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("unable to ping database: %w", err)
+		}
+		return pool, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to the database: %w", err)
+	}
+	return pool, nil
+}
+
+// sslModes are the sslmode values supported by pgx.
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// connString builds the DB connection string from the environment, read with
+// getenv. DB_SSLMODE defaults to disable. The verify modes check the server's
+// certificate against DB_SSLROOTCERT when set, or the system's CA bundle,
+// which falls back to the one embedded in the binary on scratch images.
+func connString(getenv func(string) string) (string, error) {
+	host := getenv("DB_HOST")
 	if host == "" {
 		host = "localhost"
 	}
-	connStr := fmt.Sprintf("host=%s user=jobber password=%s dbname=jobber sslmode=disable", host, os.Getenv("POSTGRES_PASSWORD"))
-	conn, err := pgxpool.New(ctx, connStr)
+	sslMode := getenv("DB_SSLMODE")
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	if !slices.Contains(sslModes, sslMode) {
+		return "", fmt.Errorf("invalid DB_SSLMODE %q, must be one of %v", sslMode, sslModes)
+	}
+	params := []string{
+		connParam("host", host),
+		connParam("user", "jobber"),
+		connParam("password", getenv("POSTGRES_PASSWORD")),
+		connParam("dbname", "jobber"),
+		connParam("sslmode", sslMode),
+	}
+	if v := getenv("DB_SSLROOTCERT"); v != "" {
+		params = append(params, connParam("sslrootcert", v))
+	}
+	return strings.Join(params, " "), nil
+}
+
+// connParam returns a key=value pair of a connection string, quoting
+// the value so it can have spaces and quotes, ie. in passwords.
+func connParam(key, value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return fmt.Sprintf("%s='%s'", key, r.Replace(value))
+}
+
+// poolConfig parses the connection string and applies the pool
+// sizing set in the environment, read with getenv.
+func poolConfig(connStr string, getenv func(string) string) (*pgxpool.Config, error) {
+	cfg, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
-		log.Error("unable to initialized db connection", slog.Any("error", err))
+		return nil, fmt.Errorf("invalid db connection string: %w", err)
+	}
+	if v := getenv("DB_MAX_CONNS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid DB_MAX_CONNS: %s", v)
+		}
+		cfg.MaxConns = int32(n)
 	}
-	if err := conn.Ping(ctx); err != nil {
-		log.Error("unable to ping database", slog.Any("error", err))
+	if v := getenv("DB_MIN_CONNS"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid DB_MIN_CONNS: %s", v)
+		}
+		cfg.MinConns = int32(n)
 	}
+	if cfg.MinConns > cfg.MaxConns {
+		return nil, fmt.Errorf("DB_MIN_CONNS (%d) can't be greater than DB_MAX_CONNS (%d)", cfg.MinConns, cfg.MaxConns)
+	}
+	if v := getenv("DB_MAX_CONN_LIFETIME"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid DB_MAX_CONN_LIFETIME: %s", v)
+		}
+		cfg.MaxConnLifetime = d
+	}
+	return cfg, nil
+}
 
-	return db.New(conn), conn.Close
+// httpTimeouts reads the HTTP server's timeouts overrides from the
+// environment, leaving the unset ones to the server's defaults.
+func httpTimeouts(getenv func(string) string) (server.Timeouts, error) {
+	var t server.Timeouts
+	for env, d := range map[string]*time.Duration{
+		"HTTP_READ_HEADER_TIMEOUT": &t.ReadHeader,
+		"HTTP_READ_TIMEOUT":        &t.Read,
+		"HTTP_WRITE_TIMEOUT":       &t.Write,
+		"HTTP_IDLE_TIMEOUT":        &t.Idle,
+	} {
+		v := getenv(env)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			return t, fmt.Errorf("invalid %s: %s", env, v)
+		}
+		*d = parsed
+	}
+	return t, nil
+}
+
+// connect calls dial until it succeeds, up to attempts times, doubling
+// the wait between them, so the DB can start after us, ie. in compose.
+func connect[T any](ctx context.Context, log *slog.Logger, attempts int, backoff time.Duration, dial func(context.Context) (T, error)) (T, error) {
+	var (
+		conn T
+		err  error
+	)
+	for i := range attempts {
+		if conn, err = dial(ctx); err == nil {
+			return conn, nil
+		}
+		if i == attempts-1 {
+			break
+		}
+		log.Warn("unable to connect, retrying", slog.Int("attempt", i+1), slog.Duration("backoff", backoff), slog.String("error", err.Error()))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return conn, ctx.Err()
+		}
+		backoff *= 2
+	}
+	return conn, fmt.Errorf("gave up after %d attempts: %w", attempts, err)
 }
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/alwedo/jobber/db"
+	"github.com/alwedo/jobber/scrape"
+	"github.com/alwedo/jobber/server"
+)
+
+func TestConnect(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	errDown := errors.New("db down")
+
+	// dialer fails until its nth call.
+	dialer := func(n int) (func(context.Context) (string, error), *int) {
+		calls := new(int)
+		return func(context.Context) (string, error) {
+			*calls++
+			if *calls < n {
+				return "", errDown
+			}
+			return "conn", nil
+		}, calls
+	}
+
+	t.Run("retries until it connects", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			dial, calls := dialer(3)
+			start := time.Now()
+			conn, err := connect(context.Background(), l, 5, time.Second, dial)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if conn != "conn" {
+				t.Errorf("expected the connection, got %q", conn)
+			}
+			if *calls != 3 {
+				t.Errorf("expected 3 attempts, got %d", *calls)
+			}
+			// It waited 1s and 2s before the second and third attempts.
+			if elapsed := time.Since(start); elapsed != 3*time.Second {
+				t.Errorf("expected to back off for 3s, got %s", elapsed)
+			}
+		})
+	})
+
+	t.Run("gives up after the attempts", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			dial, calls := dialer(10)
+			if _, err := connect(context.Background(), l, 3, time.Second, dial); !errors.Is(err, errDown) {
+				t.Errorf("expected the dial error, got: %v", err)
+			}
+			if *calls != 3 {
+				t.Errorf("expected 3 attempts, got %d", *calls)
+			}
+		})
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			dial, calls := dialer(10)
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(500*time.Millisecond, cancel)
+			if _, err := connect(ctx, l, 5, time.Second, dial); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got: %v", err)
+			}
+			if *calls != 1 {
+				t.Errorf("expected 1 attempt, got %d", *calls)
+			}
+		})
+	})
+}
+
+func TestPoolConfig(t *testing.T) {
+	const connStr = "host=localhost user=jobber dbname=jobber sslmode=disable"
+	defaults, err := poolConfig(connStr, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("unable to parse the default config: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		env             map[string]string
+		wantErr         bool
+		wantMaxConns    int32
+		wantMinConns    int32
+		wantMaxLifetime time.Duration
+	}{
+		{name: "defaults", wantMaxConns: defaults.MaxConns, wantMaxLifetime: defaults.MaxConnLifetime},
+		{
+			name:            "all set",
+			env:             map[string]string{"DB_MAX_CONNS": "20", "DB_MIN_CONNS": "2", "DB_MAX_CONN_LIFETIME": "30m"},
+			wantMaxConns:    20,
+			wantMinConns:    2,
+			wantMaxLifetime: 30 * time.Minute,
+		},
+		{name: "invalid max conns", env: map[string]string{"DB_MAX_CONNS": "many"}, wantErr: true},
+		{name: "zero max conns", env: map[string]string{"DB_MAX_CONNS": "0"}, wantErr: true},
+		{name: "negative min conns", env: map[string]string{"DB_MIN_CONNS": "-1"}, wantErr: true},
+		{name: "min conns over max conns", env: map[string]string{"DB_MAX_CONNS": "2", "DB_MIN_CONNS": "5"}, wantErr: true},
+		{name: "invalid max conn lifetime", env: map[string]string{"DB_MAX_CONN_LIFETIME": "1 hour"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := poolConfig(connStr, func(k string) string { return tt.env[k] })
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if cfg.MaxConns != tt.wantMaxConns || cfg.MinConns != tt.wantMinConns || cfg.MaxConnLifetime != tt.wantMaxLifetime {
+				t.Errorf("expected max conns %d, min conns %d and max lifetime %s, got %d, %d and %s",
+					tt.wantMaxConns, tt.wantMinConns, tt.wantMaxLifetime, cfg.MaxConns, cfg.MinConns, cfg.MaxConnLifetime)
+			}
+		})
+	}
+}
+
+func TestConnString(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: "host='localhost' user='jobber' password='' dbname='jobber' sslmode='disable'",
+		},
+		{
+			name: "require",
+			env:  map[string]string{"DB_HOST": "db.example.com", "POSTGRES_PASSWORD": "s3cr3t", "DB_SSLMODE": "require"},
+			want: "host='db.example.com' user='jobber' password='s3cr3t' dbname='jobber' sslmode='require'",
+		},
+		{
+			name: "verify-ca with root cert",
+			env:  map[string]string{"DB_SSLMODE": "verify-ca", "DB_SSLROOTCERT": "/etc/ssl/db-ca.pem"},
+			want: "host='localhost' user='jobber' password='' dbname='jobber' sslmode='verify-ca' sslrootcert='/etc/ssl/db-ca.pem'",
+		},
+		{
+			name: "verify-full",
+			env:  map[string]string{"DB_SSLMODE": "verify-full"},
+			want: "host='localhost' user='jobber' password='' dbname='jobber' sslmode='verify-full'",
+		},
+		{
+			name: "password with spaces and quotes",
+			env:  map[string]string{"POSTGRES_PASSWORD": `it's a \ secret`},
+			want: `host='localhost' user='jobber' password='it\'s a \\ secret' dbname='jobber' sslmode='disable'`,
+		},
+		{name: "invalid sslmode", env: map[string]string{"DB_SSLMODE": "always"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := connString(func(k string) string { return tt.env[k] })
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected connection string %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("pgx parses the quoted password", func(t *testing.T) {
+		const password = `it's a \ secret`
+		connStr, err := connString(func(k string) string { return map[string]string{"POSTGRES_PASSWORD": password}[k] })
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		cfg, err := poolConfig(connStr, func(string) string { return "" })
+		if err != nil {
+			t.Fatalf("unable to parse the connection string: %v", err)
+		}
+		if cfg.ConnConfig.Password != password {
+			t.Errorf("expected password %q, got %q", password, cfg.ConnConfig.Password)
+		}
+	})
+}
+
+func TestHTTPTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    server.Timeouts
+		wantErr bool
+	}{
+		{name: "unset"},
+		{
+			name: "overrides",
+			env:  map[string]string{"HTTP_WRITE_TIMEOUT": "1m", "HTTP_IDLE_TIMEOUT": "5m"},
+			want: server.Timeouts{Write: time.Minute, Idle: 5 * time.Minute},
+		},
+		{name: "invalid", env: map[string]string{"HTTP_READ_TIMEOUT": "soon"}, wantErr: true},
+		{name: "not positive", env: map[string]string{"HTTP_READ_HEADER_TIMEOUT": "0s"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := httpTimeouts(func(k string) string { return tt.env[k] })
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected timeouts %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScrapeOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    *db.Query
+		wantErr bool
+	}{
+		{
+			name: "keywords and location",
+			args: []string{"--keywords=golang", "--location=berlin"},
+			want: &db.Query{Keywords: "golang", Location: "berlin", TimePostedRange: scrape.TimePostedWeek},
+		},
+		{
+			name: "remote anywhere",
+			args: []string{"--keywords", "golang", "--remote", "--since=24h", "--geo-id=92000000"},
+			want: &db.Query{Keywords: "golang", Remote: true, TimePostedRange: scrape.TimePosted24h, GeoID: "92000000"},
+		},
+		{name: "missing location", args: []string{"--keywords=golang"}, wantErr: true},
+		{name: "invalid since", args: []string{"--keywords=golang", "--location=berlin", "--since=yesterday"}, wantErr: true},
+		{name: "unknown flag", args: []string{"--keywords=golang", "--location=berlin", "--port=80"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scrape.MockScraper.LastQuery = nil
+			var out bytes.Buffer
+			err := scrapeOnce(context.Background(), scrape.MockScraper, tt.args, &out)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				if scrape.MockScraper.LastQuery != nil {
+					t.Error("expected no scrape")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if got := scrape.MockScraper.LastQuery; *got != *tt.want {
+				t.Errorf("expected query %+v, got %+v", tt.want, got)
+			}
+			var offers []db.CreateOfferParams
+			if err := json.Unmarshal(out.Bytes(), &offers); err != nil || offers == nil {
+				t.Errorf("expected a JSON list of offers, got %q (error: %v)", out.String(), err)
+			}
+		})
+	}
+}
+
+func TestMigrateArgs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantUp    bool
+		wantSteps int
+		wantErr   bool
+	}{
+		{args: []string{"up"}, wantUp: true},
+		{args: []string{"down"}, wantSteps: 1},
+		{args: []string{"down", "3"}, wantSteps: 3},
+		{args: []string{"down", "0"}, wantErr: true},
+		{args: []string{"up", "3"}, wantErr: true},
+		{args: []string{"sideways"}, wantErr: true},
+		{args: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			up, steps, err := migrateArgs(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if up != tt.wantUp || steps != tt.wantSteps {
+				t.Errorf("expected up %t and %d steps, got %t and %d", tt.wantUp, tt.wantSteps, up, steps)
+			}
+		})
+	}
+}
@@ -53,6 +53,35 @@ var (
 		[]string{"keywords", "location"},
 	)
 
+	// Labels: "keywords", "location"
+	JobberNewOffers = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jobber_new_offers",
+			Help: "Total new Offers found by the Queries.",
+		},
+		[]string{"keywords", "location"},
+	)
+
+	// Labels: "keywords", "location"
+	JobberExpiredQueries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jobber_expired_queries",
+			Help: "Total Queries deleted for being unused.",
+		},
+		[]string{"keywords", "location"},
+	)
+
+	// Labels: "keywords", "location"
+	// Only the reads of existing queries are counted, as the labels
+	// come from the requests and would let anyone add new series.
+	FeedReads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "feed_reads_total",
+			Help: "Total feed reads per Query.",
+		},
+		[]string{"keywords", "location"},
+	)
+
 	// Labels: "portal", "keywords", "location", itemCount
 	ScraperJob = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -62,6 +91,64 @@ var (
 		},
 		[]string{"portal", "keywords", "location", "itemCount"},
 	)
+
+	// Labels: "portal", "reason"
+	ScraperErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "scraper_errors_total",
+			Help: "Total scrape errors by portal and reason.",
+		},
+		[]string{"portal", "reason"},
+	)
+
+	// Labels: "portal"
+	ScraperPaused = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "scraper_paused",
+			Help: "Whether scraping a portal is paused after too many failures.",
+		},
+		[]string{"portal"},
+	)
+
+	JobberQueuedRuns = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "jobber_queued_runs",
+			Help: "Query runs waiting for a scrape slot.",
+		},
+	)
+
+	JobberPendingRuns = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "jobber_pending_runs",
+			Help: "Queries with a run waiting or in progress.",
+		},
+	)
+
+	// Unlike JobberScheduledQueries, it's read from the scheduler,
+	// so both disagreeing tells of leaked or lost jobs.
+	JobberActiveJobs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "jobber_active_jobs",
+			Help: "Jobs in the scheduler, including the maintenance ones.",
+		},
+	)
+
+	// Labels: "cache"
+	CacheItems = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cache_items",
+			Help: "Items held by the in-memory caches.",
+		},
+		[]string{"cache"},
+	)
+
+	JobberOffersPerQuery = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "jobber_offers_per_query",
+			Help:    "Offers currently associated to each query.",
+			Buckets: []float64{0, 10, 25, 50, 100, 250, 500, 1000},
+		},
+	)
 )
 
 func Init() {
@@ -71,7 +158,17 @@ func Init() {
 		httpRequestsInFlight,
 		JobberScheduledQueries,
 		JobberNewQueries,
+		JobberNewOffers,
+		JobberExpiredQueries,
+		FeedReads,
 		ScraperJob,
+		ScraperErrors,
+		ScraperPaused,
+		JobberOffersPerQuery,
+		JobberQueuedRuns,
+		JobberPendingRuns,
+		JobberActiveJobs,
+		CacheItems,
 	)
 }
 
@@ -1,24 +1,33 @@
 package scrape
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"testing/synctest"
 	"time"
 
 	"github.com/alwedo/jobber/db"
+	"github.com/alwedo/jobber/metrics"
 	"github.com/jackc/pgx/v5/pgtype"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestFetchOffersPage(t *testing.T) {
 	mockResp := newLinkedInMockResp(t)
-	l := &linkedIn{&http.Client{Transport: mockResp}}
+	l := newTestLinkedIn(mockResp)
 	ctx := context.Background()
 
 	t.Run("first time query", func(t *testing.T) {
@@ -26,7 +35,7 @@ func TestFetchOffersPage(t *testing.T) {
 			Keywords: "golang",
 			Location: "the moon",
 		}
-		resp, err := l.fetchOffersPage(ctx, query, 0)
+		resp, err := l.fetchOffersPage(ctx, query, 0, &pacer{})
 		if err != nil {
 			t.Errorf("error fetching offers: %s", err.Error())
 		}
@@ -65,13 +74,38 @@ func TestFetchOffersPage(t *testing.T) {
 		}
 	})
 
+	t.Run("requests have a browser-like User-Agent by default", func(t *testing.T) {
+		query := &db.Query{Keywords: "golang", Location: "the moon"}
+		resp, err := l.fetchOffersPage(ctx, query, 0, &pacer{})
+		if err != nil {
+			t.Errorf("error fetching offers: %s", err.Error())
+		}
+		defer resp.Close()
+		if ua := mockResp.req.Header.Get("User-Agent"); ua != defaultUserAgent {
+			t.Errorf("expected User-Agent to be %q, got %q", defaultUserAgent, ua)
+		}
+	})
+
+	t.Run("requests use the configured User-Agent", func(t *testing.T) {
+		ll := newTestLinkedIn(mockResp, WithUserAgent("jobber-test/1.0"))
+		query := &db.Query{Keywords: "golang", Location: "the moon"}
+		resp, err := ll.fetchOffersPage(ctx, query, 0, &pacer{})
+		if err != nil {
+			t.Errorf("error fetching offers: %s", err.Error())
+		}
+		defer resp.Close()
+		if ua := mockResp.req.Header.Get("User-Agent"); ua != "jobber-test/1.0" {
+			t.Errorf("expected User-Agent to be 'jobber-test/1.0', got %q", ua)
+		}
+	})
+
 	t.Run("queries with UpdatedAt field should have relative FTPR", func(t *testing.T) {
 		query := &db.Query{
 			Keywords:  "golang",
 			Location:  "the moon",
 			UpdatedAt: pgtype.Timestamptz{Valid: true, Time: time.Now().Add(-time.Hour)},
 		}
-		resp, err := l.fetchOffersPage(ctx, query, 0)
+		resp, err := l.fetchOffersPage(ctx, query, 0, &pacer{})
 		if err != nil {
 			t.Errorf("error fetching offers: %s", err.Error())
 		}
@@ -82,6 +116,92 @@ func TestFetchOffersPage(t *testing.T) {
 		}
 	})
 
+	t.Run("first scrapes use the query's time posted range", func(t *testing.T) {
+		tests := []struct {
+			timePostedRange string
+			wantFTPR        string
+		}{
+			{timePostedRange: "", wantFTPR: "r604800"},
+			{timePostedRange: TimePosted24h, wantFTPR: "r86400"},
+			{timePostedRange: TimePostedWeek, wantFTPR: "r604800"},
+			{timePostedRange: TimePostedMonth, wantFTPR: "r2592000"},
+			{timePostedRange: TimePostedAll, wantFTPR: ""},
+		}
+		for _, tt := range tests {
+			query := &db.Query{Keywords: "golang", Location: "the moon", TimePostedRange: tt.timePostedRange}
+			resp, err := l.fetchOffersPage(ctx, query, 0, &pacer{})
+			if err != nil {
+				t.Fatalf("error fetching offers: %s", err.Error())
+			}
+			resp.Close()
+			values := mockResp.req.URL.Query()
+			if got := values.Get(paramFTPR); got != tt.wantFTPR {
+				t.Errorf("expected f_TPR to be %q for range %q, got %q", tt.wantFTPR, tt.timePostedRange, got)
+			}
+			if tt.wantFTPR == "" && values.Has(paramFTPR) {
+				t.Errorf("expected no f_TPR for range %q", tt.timePostedRange)
+			}
+		}
+	})
+
+	t.Run("remote queries filter by workplace type", func(t *testing.T) {
+		tests := []struct {
+			name         string
+			query        *db.Query
+			wantFWT      string
+			wantLocation bool
+		}{
+			{name: "not remote", query: &db.Query{Keywords: "golang", Location: "the moon"}, wantLocation: true},
+			{name: "remote", query: &db.Query{Keywords: "golang", Location: "the moon", Remote: true}, wantFWT: workplaceRemote, wantLocation: true},
+			{name: "remote anywhere", query: &db.Query{Keywords: "golang", Remote: true}, wantFWT: workplaceRemote},
+		}
+		for _, tt := range tests {
+			resp, err := l.fetchOffersPage(ctx, tt.query, 0, &pacer{})
+			if err != nil {
+				t.Fatalf("error fetching offers: %s", err.Error())
+			}
+			resp.Close()
+			values := mockResp.req.URL.Query()
+			if got := values.Get(paramFWT); got != tt.wantFWT {
+				t.Errorf("%s: expected f_WT to be %q, got %q", tt.name, tt.wantFWT, got)
+			}
+			if tt.wantFWT == "" && values.Has(paramFWT) {
+				t.Errorf("%s: expected no f_WT", tt.name)
+			}
+			if got := values.Has(paramLocation); got != tt.wantLocation {
+				t.Errorf("%s: expected location param %t, got %t", tt.name, tt.wantLocation, got)
+			}
+		}
+	})
+
+	t.Run("queries with a geo id send it", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			query     *db.Query
+			wantGeoID string
+		}{
+			{name: "location name", query: &db.Query{Keywords: "golang", Location: "berlin"}},
+			{name: "geo id", query: &db.Query{Keywords: "golang", Location: "berlin", GeoID: "106967730"}, wantGeoID: "106967730"},
+		}
+		for _, tt := range tests {
+			resp, err := l.fetchOffersPage(ctx, tt.query, 0, &pacer{})
+			if err != nil {
+				t.Fatalf("error fetching offers: %s", err.Error())
+			}
+			resp.Close()
+			values := mockResp.req.URL.Query()
+			if got := values.Get(paramGeoID); got != tt.wantGeoID {
+				t.Errorf("%s: expected geoId to be %q, got %q", tt.name, tt.wantGeoID, got)
+			}
+			if tt.wantGeoID == "" && values.Has(paramGeoID) {
+				t.Errorf("%s: expected no geoId", tt.name)
+			}
+			if got := values.Get(paramLocation); got != "berlin" {
+				t.Errorf("%s: expected location to be kept, got %q", tt.name, got)
+			}
+		}
+	})
+
 	t.Run("retryable cases", func(t *testing.T) {
 		t.Run("working exponential backoff", func(t *testing.T) {
 			synctest.Test(t, func(t *testing.T) {
@@ -91,7 +211,7 @@ func TestFetchOffersPage(t *testing.T) {
 				}
 				pages := []int{0, 10, 20}
 				for _, p := range pages {
-					resp, err := l.fetchOffersPage(ctx, query, p)
+					resp, err := l.fetchOffersPage(ctx, query, p, &pacer{})
 					if err != nil {
 						t.Errorf("expected no error, got: %v", err)
 					}
@@ -113,7 +233,7 @@ func TestFetchOffersPage(t *testing.T) {
 				for _, p := range pages {
 					switch p {
 					case 0:
-						resp, err := l.fetchOffersPage(ctx, query, p)
+						resp, err := l.fetchOffersPage(ctx, query, p, &pacer{})
 						if err != nil {
 							t.Errorf("expected no error, got: %v", err)
 						}
@@ -121,10 +241,13 @@ func TestFetchOffersPage(t *testing.T) {
 							t.Errorf("expected response body not to be nil")
 						}
 					default:
-						resp, err := l.fetchOffersPage(ctx, query, p)
+						resp, err := l.fetchOffersPage(ctx, query, p, &pacer{})
 						if !errors.Is(err, ErrRetryable) {
 							t.Errorf("expected err to be ErrRetryable, got: %v", err)
 						}
+						if err != nil && !strings.Contains(err.Error(), strconv.Itoa(http.StatusTooManyRequests)) {
+							t.Errorf("expected err to contain the last status code %d, got: %v", http.StatusTooManyRequests, err)
+						}
 						if resp != nil {
 							t.Errorf("expected response body to be nil, got %v", resp)
 						}
@@ -136,8 +259,316 @@ func TestFetchOffersPage(t *testing.T) {
 	})
 }
 
+func TestLinkedInSearchURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *db.Query
+		want  string
+	}{
+		{name: "location name", query: &db.Query{Keywords: "golang", Location: "berlin"}, want: "keywords=golang&location=berlin"},
+		{name: "remote", query: &db.Query{Keywords: "golang", Location: "berlin", Remote: true}, want: "f_WT=2&keywords=golang&location=berlin"},
+		{name: "remote anywhere", query: &db.Query{Keywords: "golang", Remote: true}, want: "f_WT=2&keywords=golang"},
+		{name: "geo id", query: &db.Query{Keywords: "golang", Location: "berlin", GeoID: "106967730"}, want: "geoId=106967730&keywords=golang&location=berlin"},
+	}
+	for _, tt := range tests {
+		if got := LinkedInSearchURL(tt.query); got != linkedInURL+"?"+tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, linkedInURL+"?"+tt.want, got)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	l := newTestLinkedIn(nil)
+
+	for retries := range maxRetries + 5 {
+		want := min(backoffBase<<retries, backoffCap)
+		got := l.backoff(retries)
+		if got < want || got >= want+backoffBase {
+			t.Errorf("expected backoff for %d retries to be in [%v, %v), got %v", retries, want, want+backoffBase, got)
+		}
+	}
+
+	t.Run("jitter is reproducible with the same source", func(t *testing.T) {
+		a, b := newTestLinkedIn(nil), newTestLinkedIn(nil)
+		for retries := range maxRetries {
+			if a.backoff(retries) != b.backoff(retries) {
+				t.Errorf("expected same jitter for the same seed on retry %d", retries)
+			}
+		}
+	})
+}
+
+func TestScraperErrors(t *testing.T) {
+	l := newTestLinkedIn(newLinkedInMockResp(t))
+	query := &db.Query{
+		// retry-fail keyword makes mock to return 429 all the time after the first call.
+		Keywords: "retry-fail",
+		Location: "the moon",
+	}
+
+	synctest.Test(t, func(t *testing.T) {
+		retryable := scraperErrors(t, ReasonRetryable)
+		exhausted := scraperErrors(t, ReasonExhausted)
+		_, err := l.Scrape(context.Background(), query)
+		var sErr *Error
+		if !errors.As(err, &sErr) || sErr.Reason != ReasonExhausted {
+			t.Errorf("expected a %s error, got: %v", ReasonExhausted, err)
+		}
+		if !errors.Is(err, ErrRetryable) {
+			t.Errorf("expected err to be ErrRetryable, got: %v", err)
+		}
+		if got := scraperErrors(t, ReasonRetryable) - retryable; got != maxRetries+1 {
+			t.Errorf("expected %d retryable errors, got %v", maxRetries+1, got)
+		}
+		// Exhausted retries are counted by the scrape's caller.
+		if got := scraperErrors(t, ReasonExhausted) - exhausted; got != 0 {
+			t.Errorf("expected no exhausted errors counted, got %v", got)
+		}
+	})
+}
+
+func TestMaxRetries(t *testing.T) {
+	// retry-fail keyword makes mock to return 429 all the time after the first call.
+	query := &db.Query{Keywords: "retry-fail", Location: "the moon"}
+
+	synctest.Test(t, func(t *testing.T) {
+		mockResp := newLinkedInMockResp(t)
+		l := newTestLinkedIn(mockResp, WithMaxRetries(1), WithBackoffBase(time.Millisecond))
+		_, err := l.fetchOffersPage(context.Background(), query, searchInterval, &pacer{})
+		if !errors.Is(err, ErrRetryable) {
+			t.Errorf("expected ErrRetryable, got: %v", err)
+		}
+		// The first request and its single retry.
+		if mockResp.throttled != 2 {
+			t.Errorf("expected 2 throttled requests, got %d", mockResp.throttled)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := LinkedIn(WithMaxRetries(-1)); err == nil {
+			t.Error("expected an error for negative max retries")
+		}
+		if _, err := LinkedIn(WithBackoffBase(-time.Second)); err == nil {
+			t.Error("expected an error for a negative backoff base")
+		}
+	})
+}
+
+func TestBackoffCancel(t *testing.T) {
+	// retry-fail keyword makes mock to return 429 all the time after the first call.
+	query := &db.Query{Keywords: "retry-fail", Location: "the moon"}
+
+	synctest.Test(t, func(t *testing.T) {
+		l := newTestLinkedIn(newLinkedInMockResp(t), WithBackoffBase(time.Minute))
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(time.Second, cancel)
+
+		start := time.Now()
+		_, err := l.fetchOffersPage(ctx, query, searchInterval, &pacer{})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed != time.Second {
+			t.Errorf("expected the backoff to be interrupted after 1s, got %s", elapsed)
+		}
+	})
+}
+
+func TestPageDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		keywords string
+		opts     []LinkedInOption
+		want     time.Duration
+	}{
+		// The mock paginates 3 pages, so the scrape waits twice.
+		{name: "delay between pages", keywords: "golang", want: 2 * pageDelay},
+		{name: "custom delay", keywords: "golang", opts: []LinkedInOption{WithPageDelay(5 * time.Second)}, want: 10 * time.Second},
+		{name: "no delay for a single page", keywords: "single-page", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synctest.Test(t, func(t *testing.T) {
+				l := newTestLinkedIn(newLinkedInMockResp(t), tt.opts...)
+				start := time.Now()
+				if _, err := l.Scrape(context.Background(), &db.Query{Keywords: tt.keywords, Location: "the moon"}); err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				if elapsed := time.Since(start); elapsed != tt.want {
+					t.Errorf("expected the scrape to take %s, got %s", tt.want, elapsed)
+				}
+			})
+		})
+	}
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			l := newTestLinkedIn(newLinkedInMockResp(t), WithPageDelay(time.Minute))
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(time.Second, cancel)
+			offers, err := l.Scrape(ctx, &db.Query{Keywords: "golang", Location: "the moon"})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got: %v", err)
+			}
+			if len(offers) != searchInterval {
+				t.Errorf("expected the first page's offers, got %d", len(offers))
+			}
+		})
+	})
+
+	t.Run("negative delay", func(t *testing.T) {
+		if _, err := LinkedIn(WithPageDelay(-time.Second)); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestScrapeDebug(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		l := newTestLinkedIn(newLinkedInMockResp(t))
+		offers, info, err := l.ScrapeDebug(context.Background(), &db.Query{Keywords: "golang", Location: "the moon"})
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		// The mock paginates the 3 fixtures, with 10, 10 and 7 offers.
+		want := DebugInfo{Pages: 3, Items: 27, Cards: 27, IDs: 27, URLs: 27, Titles: 27, Companies: 27, Dates: 27, Offers: len(offers)}
+		if *info != want {
+			t.Errorf("expected debug info %+v, got %+v", want, *info)
+		}
+		if len(offers) != 27 {
+			t.Errorf("expected 27 offers, got %d", len(offers))
+		}
+	})
+
+	t.Run("broken selectors", func(t *testing.T) {
+		body := `<ul><li><div class="base-search-card"><span class="renamed-title">Gopher</span></div></li><li>Ad</li></ul>`
+		info := &DebugInfo{}
+		if _, err := newTestLinkedIn(nil).parseLinkedInPage(io.NopCloser(strings.NewReader(body)), info); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		want := DebugInfo{Pages: 1, Items: 2, Cards: 1, Skipped: 1}
+		if *info != want {
+			t.Errorf("expected debug info %+v, got %+v", want, *info)
+		}
+	})
+}
+
+func TestRetryBudget(t *testing.T) {
+	// Every page takes 2 retries, so the third one exceeds the budget.
+	const budget = 5
+	query := &db.Query{Keywords: "retry-twice", Location: "the moon"}
+
+	t.Run("scrapes stop once they spend their retry budget", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			mockResp := newLinkedInMockResp(t)
+			l := newTestLinkedIn(mockResp, WithRetryBudget(budget))
+			offers, err := l.Scrape(context.Background(), query)
+			if !errors.Is(err, ErrRetryable) {
+				t.Errorf("expected ErrRetryable, got: %v", err)
+			}
+			if len(offers) != 2*searchInterval {
+				t.Errorf("expected the offers of the first 2 pages, got %d", len(offers))
+			}
+			if mockResp.throttled != budget+1 {
+				t.Errorf("expected %d throttled requests, got %d", budget+1, mockResp.throttled)
+			}
+		})
+	})
+
+	t.Run("scrapes without budget only stop at each request's retries", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			l := newTestLinkedIn(newLinkedInMockResp(t), WithRetryBudget(0))
+			if _, err := l.Scrape(context.Background(), query); err != nil {
+				t.Errorf("expected no error, got: %v", err)
+			}
+		})
+	})
+
+	t.Run("negative budget", func(t *testing.T) {
+		if _, err := LinkedIn(WithRetryBudget(-1)); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func scraperErrors(t *testing.T, reason string) float64 {
+	t.Helper()
+	m := &dto.Metric{}
+	if err := metrics.ScraperErrors.WithLabelValues(SourceLinkedIn, reason).Write(m); err != nil {
+		t.Fatalf("unable to read scraper errors metric: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestWithProxy(t *testing.T) {
+	t.Run("requests flow through the proxy", func(t *testing.T) {
+		proxied := make(chan string, 1)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied <- r.Method + " " + r.Host
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer proxy.Close()
+
+		l, err := LinkedIn(WithProxy(proxy.URL))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		l.fetchOffersPage(context.Background(), &db.Query{Keywords: "golang", Location: "berlin"}, 0, &pacer{}) //nolint: errcheck
+		select {
+		case got := <-proxied:
+			if got != "CONNECT www.linkedin.com:443" {
+				t.Errorf("expected the proxy to tunnel to LinkedIn, got %q", got)
+			}
+		default:
+			t.Errorf("expected the request to go through the proxy")
+		}
+	})
+
+	t.Run("invalid proxy urls are rejected", func(t *testing.T) {
+		for _, u := range []string{"://proxy", "ftp://proxy:21", "http://"} {
+			if _, err := LinkedIn(WithProxy(u)); err == nil {
+				t.Errorf("expected an error for proxy url %q", u)
+			}
+		}
+	})
+}
+
+func TestWithoutHTTP2(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []LinkedInOption
+		wantHTTP1 bool
+	}{
+		{name: "HTTP/2 by default", wantHTTP1: false},
+		{name: "HTTP/1.1 when disabled", opts: []LinkedInOption{WithoutHTTP2()}, wantHTTP1: true},
+		{name: "HTTP/1.1 when disabled with proxy", opts: []LinkedInOption{WithoutHTTP2(), WithProxy("http://proxy:8080")}, wantHTTP1: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := LinkedIn(tt.opts...)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if l.client.Transport == nil {
+				if tt.wantHTTP1 {
+					t.Fatalf("expected a transport configured for HTTP/1.1, got the default one")
+				}
+				return
+			}
+			tr, ok := l.client.Transport.(*http.Transport)
+			if !ok {
+				t.Fatalf("expected an *http.Transport, got %T", l.client.Transport)
+			}
+			gotHTTP1 := !tr.ForceAttemptHTTP2 && tr.TLSNextProto != nil && len(tr.TLSNextProto) == 0
+			if gotHTTP1 != tt.wantHTTP1 {
+				t.Errorf("expected HTTP/1.1 only to be %t, got %t", tt.wantHTTP1, gotHTTP1)
+			}
+		})
+	}
+}
+
 func TestParseLinkedInBody(t *testing.T) {
-	l := &linkedIn{}
+	l := newTestLinkedIn(nil)
 
 	file, err := os.Open("test_data/linkedin1.html")
 	if err != nil {
@@ -155,6 +586,13 @@ func TestParseLinkedInBody(t *testing.T) {
 	if jobs[0].ID != "4322119156" {
 		t.Errorf("expected job ID 4322119156, got %s", jobs[0].ID)
 	}
+	wantURL := "https://de.linkedin.com/jobs/view/software-engineer-golang-at-delivery-hero-4322119156?position=1&pageNum=0&refId=t1a9JzPH4Utcs7ySgbdWwg%3D%3D&trackingId=1D%2BIhVM0MECQslSd3xM%2FOA%3D%3D"
+	if jobs[0].Url != wantURL {
+		t.Errorf("expected job url '%s', got '%s'", wantURL, jobs[0].Url)
+	}
+	if jobs[0].Source != SourceLinkedIn {
+		t.Errorf("expected job source '%s', got '%s'", SourceLinkedIn, jobs[0].Source)
+	}
 	if jobs[0].Title != "Software Engineer (Golang)" {
 		t.Errorf("expected job title 'Software Engineer (Golang)', got '%s'", jobs[0].Title)
 	}
@@ -164,14 +602,206 @@ func TestParseLinkedInBody(t *testing.T) {
 	if jobs[0].Company != "Delivery Hero" {
 		t.Errorf("expected job company 'Delivery Hero', got '%s'", jobs[0].Company)
 	}
+	if !strings.HasPrefix(jobs[0].LogoUrl, "https://media.licdn.com/dms/image/v2/D4E0BAQFOZbu6XEJUAw/company-logo_100_100/") {
+		t.Errorf("expected job logo url to be Delivery Hero's logo, got '%s'", jobs[0].LogoUrl)
+	}
+	if jobs[0].Salary != "" {
+		t.Errorf("expected job without salary info to have empty salary, got '%s'", jobs[0].Salary)
+	}
 	if jobs[0].PostedAt.Time.Format("2006-01-02") != "2025-11-13" {
 		t.Errorf("expected job posted at time %v, got %v", "2025-11-13", jobs[0].PostedAt.Time.Format("2006-01-02"))
 	}
 }
 
+func TestParseLinkedInInvalidCards(t *testing.T) {
+	var logs bytes.Buffer
+	l := newTestLinkedIn(nil, WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))))
+
+	file, err := os.Open("test_data/linkedin_malformed.html")
+	if err != nil {
+		t.Fatalf("failed to open file: %s", err.Error())
+	}
+	defer file.Close()
+
+	info := &DebugInfo{}
+	jobs, err := l.parseLinkedInPage(file, info)
+	if err != nil {
+		t.Fatalf("error parsing test_data/linkedin_malformed.html: %s", err.Error())
+	}
+	if len(jobs) != 1 || jobs[0].ID != "4335565666" {
+		t.Errorf("expected only the valid job 4335565666, got %+v", jobs)
+	}
+	if info.Skipped != 2 {
+		t.Errorf("expected 2 skipped cards, got %d", info.Skipped)
+	}
+	if got := strings.Count(logs.String(), "skipping invalid card"); got != 2 {
+		t.Errorf("expected 2 skipped cards logged, got %d in logs: %s", got, logs.String())
+	}
+}
+
+func TestParseLinkedInJunkTitles(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []LinkedInOption
+		wantIDs   []string
+		wantDebug int
+	}{
+		{name: "default min length", wantIDs: []string{"4335565666"}, wantDebug: 1},
+		{name: "longer min length", opts: []LinkedInOption{WithMinTitleLength(50)}, wantDebug: 2},
+		{name: "no min length", opts: []LinkedInOption{WithMinTitleLength(0)}, wantIDs: []string{"4335565666", "4335565668"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			opts := append([]LinkedInOption{WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))}, tt.opts...)
+			l := newTestLinkedIn(nil, opts...)
+
+			file, err := os.Open("test_data/linkedin_junk_title.html")
+			if err != nil {
+				t.Fatalf("failed to open file: %s", err.Error())
+			}
+			defer file.Close()
+
+			jobs, err := l.parseLinkedInBody(file)
+			if err != nil {
+				t.Fatalf("error parsing test_data/linkedin_junk_title.html: %s", err.Error())
+			}
+			var gotIDs []string
+			for _, j := range jobs {
+				gotIDs = append(gotIDs, j.ID)
+			}
+			if !slices.Equal(gotIDs, tt.wantIDs) {
+				t.Errorf("expected jobs %v, got %v", tt.wantIDs, gotIDs)
+			}
+			if got := strings.Count(logs.String(), "level=DEBUG msg=\"dropping junk title"); got != tt.wantDebug {
+				t.Errorf("expected %d junk titles logged at debug, got %d in logs: %s", tt.wantDebug, got, logs.String())
+			}
+		})
+	}
+
+	if _, err := LinkedIn(WithMinTitleLength(-1)); err == nil {
+		t.Error("expected a negative min title length to be rejected")
+	}
+}
+
+func TestParseLinkedInSalary(t *testing.T) {
+	l := newTestLinkedIn(nil)
+
+	file, err := os.Open("test_data/linkedin_salary.html")
+	if err != nil {
+		t.Fatalf("failed to open file: %s", err.Error())
+	}
+	defer file.Close()
+
+	jobs, err := l.parseLinkedInBody(file)
+	if err != nil {
+		t.Fatalf("error parsing test_data/linkedin_salary.html: %s", err.Error())
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(jobs))
+	}
+	if jobs[0].Salary != "€70,000.00 - €90,000.00" {
+		t.Errorf("expected job salary '€70,000.00 - €90,000.00', got '%s'", jobs[0].Salary)
+	}
+	if jobs[0].Title != "Backend Engineer (Golang)" {
+		t.Errorf("expected job title 'Backend Engineer (Golang)', got '%s'", jobs[0].Title)
+	}
+}
+
+func TestParseLinkedInRelativeTime(t *testing.T) {
+	l := newTestLinkedIn(nil)
+
+	file, err := os.Open("test_data/linkedin_relative_time.html")
+	if err != nil {
+		t.Fatalf("failed to open file: %s", err.Error())
+	}
+	defer file.Close()
+
+	jobs, err := l.parseLinkedInBody(file)
+	if err != nil {
+		t.Fatalf("error parsing test_data/linkedin_relative_time.html: %s", err.Error())
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(jobs))
+	}
+	want := time.Now().Add(-3 * time.Hour)
+	if got := jobs[0].PostedAt.Time; got.Sub(want).Abs() > time.Minute {
+		t.Errorf("expected job posted at about %v, got %v", want, got)
+	}
+
+	t.Run("relative phrases", func(t *testing.T) {
+		now := time.Date(2025, 11, 13, 12, 0, 0, 0, time.UTC)
+		tests := []struct {
+			text   string
+			want   time.Time
+			wantOK bool
+		}{
+			{text: "Just now", want: now, wantOK: true},
+			{text: "30 seconds ago", want: now.Add(-30 * time.Second), wantOK: true},
+			{text: "1 minute ago", want: now.Add(-time.Minute), wantOK: true},
+			{text: "an hour ago", want: now.Add(-time.Hour), wantOK: true},
+			{text: "\n      3 hours ago\n  ", want: now.Add(-3 * time.Hour), wantOK: true},
+			{text: "1 day ago", want: now.AddDate(0, 0, -1), wantOK: true},
+			{text: "2 weeks ago", want: now.AddDate(0, 0, -14), wantOK: true},
+			{text: "1 month ago", want: now.AddDate(0, -1, 0), wantOK: true},
+			{text: "a year ago", want: now.AddDate(-1, 0, 0), wantOK: true},
+			{text: "yesterday", wantOK: false},
+			{text: "", wantOK: false},
+		}
+		for _, tt := range tests {
+			got, ok := parseRelativeTime(tt.text, now)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("expected %q to parse to %v (%t), got %v (%t)", tt.text, tt.want, tt.wantOK, got, ok)
+			}
+		}
+	})
+
+	t.Run("unparsable date", func(t *testing.T) {
+		var logs bytes.Buffer
+		l := newTestLinkedIn(nil, WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))))
+		body := `<ul><li><div class="base-search-card" data-entity-urn="urn:li:jobPosting:42">` +
+			`<h3 class="base-search-card__title">Gopher</h3>` +
+			`<time datetime="13/11/2025">sometime</time></div></li></ul>`
+		jobs, err := l.parseLinkedInBody(io.NopCloser(strings.NewReader(body)))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if len(jobs) != 1 {
+			t.Fatalf("expected 1 job, got %d", len(jobs))
+		}
+		if got := jobs[0].PostedAt.Time; time.Since(got).Abs() > time.Minute {
+			t.Errorf("expected the job to fall back to being posted now, got %v", got)
+		}
+		if !strings.Contains(logs.String(), "unable to parse posted date") || !strings.Contains(logs.String(), "id=42") {
+			t.Errorf("expected a warning with the job's ID, got logs: %s", logs.String())
+		}
+	})
+}
+
+func TestParseLinkedInDescription(t *testing.T) {
+	file, err := os.Open("test_data/linkedin_detail.html")
+	if err != nil {
+		t.Fatalf("failed to open file: %s", err.Error())
+	}
+
+	d, err := parseLinkedInDescription(file)
+	if err != nil {
+		t.Fatalf("error parsing test_data/linkedin_detail.html: %s", err.Error())
+	}
+	want := "<p>We&#39;re looking for a <strong>Backend Engineer</strong> to build our Go services.</p><ul><li>5+ years of Go</li><li>PostgreSQL</li></ul>"
+	if d != want {
+		t.Errorf("expected description %q, got %q", want, d)
+	}
+}
+
 func TestScrape(t *testing.T) {
 	mockResp := newLinkedInMockResp(t)
-	l := &linkedIn{&http.Client{Transport: mockResp}}
+	l := newTestLinkedIn(mockResp)
+
+	if l.Name() != SourceLinkedIn {
+		t.Errorf("expected name '%s', got '%s'", SourceLinkedIn, l.Name())
+	}
 
 	t.Run("expected behaviour", func(t *testing.T) {
 		synctest.Test(t, func(t *testing.T) {
@@ -186,6 +816,82 @@ func TestScrape(t *testing.T) {
 			}
 		})
 	})
+	t.Run("throttling carries over to the next pages", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			mockResp.throttled, mockResp.lastReq = 0, time.Time{}
+			query := &db.Query{Keywords: "retry", Location: "the moon"}
+			pace := &pacer{}
+			for _, p := range []int{0, 10} {
+				resp, err := l.fetchOffersPage(context.Background(), query, p, pace)
+				if err != nil {
+					t.Fatalf("expected no error, got: %v", err)
+				}
+				resp.Close()
+			}
+			if pace.delay < backoffBase {
+				t.Errorf("expected a carried over delay of at least %v after throttling, got %v", backoffBase, pace.delay)
+			}
+			// The third page starts spaced by the carried over delay, so it isn't throttled.
+			resp, err := l.fetchOffersPage(context.Background(), query, 20, pace)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			resp.Close()
+			synctest.Wait()
+			if mockResp.throttled != 1 {
+				t.Errorf("expected only the second page to be throttled, got %d throttled requests", mockResp.throttled)
+			}
+		})
+	})
+	t.Run("offers repeated across pages are deduplicated", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			query := &db.Query{Keywords: "overlap", Location: "the moon"}
+			offers, err := l.Scrape(context.Background(), query)
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			synctest.Wait()
+			if len(offers) != 17 { // The second page repeats the first one.
+				t.Errorf("expected 17 offers, got %d", len(offers))
+			}
+			seen := make(map[string]bool)
+			for _, o := range offers {
+				if seen[o.ID] {
+					t.Errorf("expected unique offer IDs, got %s more than once", o.ID)
+				}
+				seen[o.ID] = true
+			}
+		})
+	})
+	t.Run("oversized responses are rejected", func(t *testing.T) {
+		ll := newTestLinkedIn(mockResp, WithMaxBodySize(1024))
+		query := &db.Query{Keywords: "golang", Location: "the moon"}
+		offers, err := ll.Scrape(context.Background(), query)
+		if !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("expected ErrBodyTooLarge, got: %v", err)
+		}
+		if len(offers) != 0 {
+			t.Errorf("expected no offers, got %d", len(offers))
+		}
+	})
+	t.Run("descriptions are fetched when enabled", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			query := &db.Query{Keywords: "golang", Location: "the moon"}
+			for _, enabled := range []bool{false, true} {
+				ll := newTestLinkedIn(mockResp, WithDescriptions(enabled))
+				offers, err := ll.Scrape(context.Background(), query)
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				for _, o := range offers {
+					if gotDescription := o.Description != ""; gotDescription != enabled {
+						t.Fatalf("expected offer %s to have a description %t, got %q", o.ID, enabled, o.Description)
+					}
+				}
+			}
+			synctest.Wait()
+		})
+	})
 	t.Run("too many retries don't discard data", func(t *testing.T) {
 		synctest.Test(t, func(t *testing.T) {
 			query := &db.Query{Keywords: "retry-fail", Location: "the moon"}
@@ -202,9 +908,11 @@ func TestScrape(t *testing.T) {
 }
 
 type linkedInMockResp struct {
-	t       testing.TB
-	req     *http.Request
-	lastReq time.Time
+	t         testing.TB
+	req       *http.Request
+	lastReq   time.Time
+	throttled int            // Amount of 429 responses.
+	attempts  map[string]int // Requests per URL.
 }
 
 func (h *linkedInMockResp) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -227,8 +935,22 @@ func (h *linkedInMockResp) RoundTrip(req *http.Request) (*http.Response, error)
 		status = http.StatusTooManyRequests
 	}
 
+	// The keyword 'retry-twice' returns 429 twice for every page before returning it.
+	if req.URL.Query().Get(paramKeywords) == "retry-twice" {
+		if h.attempts == nil {
+			h.attempts = make(map[string]int)
+		}
+		h.attempts[req.URL.String()]++
+		if h.attempts[req.URL.String()] <= 2 {
+			status = http.StatusTooManyRequests
+		}
+	}
+
 	// Mock LinkedIn pagination strategy
 	fn := "test_data/linkedin1.html"
+	if strings.HasPrefix(req.URL.String(), linkedInJobURL) {
+		fn = "test_data/linkedin_detail.html"
+	}
 	switch req.URL.Query().Get("start") {
 	case "10":
 		fn = "test_data/linkedin2.html"
@@ -236,6 +958,20 @@ func (h *linkedInMockResp) RoundTrip(req *http.Request) (*http.Response, error)
 		fn = "test_data/linkedin3.html"
 	}
 
+	// The keyword 'overlap' returns the first page again as the second one.
+	if req.URL.Query().Get(paramKeywords) == "overlap" && req.URL.Query().Get("start") == "10" {
+		fn = "test_data/linkedin1.html"
+	}
+
+	// The keyword 'single-page' returns a last page right away.
+	if req.URL.Query().Get(paramKeywords) == "single-page" {
+		fn = "test_data/linkedin3.html"
+	}
+
+	if status == http.StatusTooManyRequests {
+		h.throttled++
+	}
+
 	// Return the html according to pagination
 	body, err := os.Open(fn)
 	if err != nil {
@@ -251,6 +987,18 @@ func (h *linkedInMockResp) RoundTrip(req *http.Request) (*http.Response, error)
 	}, nil
 }
 
+// newTestLinkedIn returns a linkedIn scraper using the passed RoundTripper
+// and a seeded jitter source so backoff timings are reproducible.
+func newTestLinkedIn(rt http.RoundTripper, opts ...LinkedInOption) *linkedIn {
+	l, err := LinkedIn(opts...)
+	if err != nil {
+		panic(err)
+	}
+	l.client = &http.Client{Transport: rt}
+	l.rand = rand.New(rand.NewPCG(1, 2)) //nolint: gosec
+	return l
+}
+
 func newLinkedInMockResp(t testing.TB) *linkedInMockResp {
 	return &linkedInMockResp{t: t}
 }
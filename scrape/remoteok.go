@@ -0,0 +1,141 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alwedo/jobber/db"
+	"github.com/alwedo/jobber/metrics"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	remoteOKURL = "https://remoteok.com/api"
+	// RemoteOK IDs are prefixed so they don't collide with other portals' IDs.
+	remoteOKIDPrefix = "remoteok-"
+	// RemoteOK rejects requests without a User-Agent.
+	remoteOKUserAgent = "rssjobs (+https://rssjobs.app)"
+)
+
+var errDecode = errors.New("failed to decode response body")
+
+type remoteOK struct {
+	client *http.Client
+}
+
+func RemoteOK() *remoteOK { //nolint: revive
+	return &remoteOK{client: http.DefaultClient}
+}
+
+// remoteOKJob is a job offer as returned by RemoteOK's API.
+// The API's first element is a legal notice without ID.
+type remoteOKJob struct {
+	ID        string   `json:"id"`
+	Epoch     int64    `json:"epoch"`
+	Company   string   `json:"company"`
+	Position  string   `json:"position"`
+	Tags      []string `json:"tags"`
+	Location  string   `json:"location"`
+	SalaryMin int      `json:"salary_min"`
+	SalaryMax int      `json:"salary_max"`
+	Logo      string   `json:"logo"`
+	URL       string   `json:"url"`
+}
+
+func (r *remoteOK) Name() string { return SourceRemoteOK }
+
+// Scrape fetches all the offers from RemoteOK's API and returns
+// the ones matching the query's keywords and location.
+// RemoteOK's API doesn't support searching, so filtering is done here.
+func (r *remoteOK) Scrape(ctx context.Context, query *db.Query) ([]db.CreateOfferParams, error) {
+	jobs, err := r.fetchOffers(ctx)
+	if err != nil {
+		if errors.Is(err, errDecode) {
+			return nil, &Error{Reason: ReasonParse, Err: fmt.Errorf("failed to fetchOffers in remoteOK.Scrape: %w", err)}
+		}
+		return nil, fetchError(fmt.Errorf("failed to fetchOffers in remoteOK.Scrape: %w", err))
+	}
+
+	var offers []db.CreateOfferParams
+	for _, j := range jobs {
+		if j.ID == "" || !matchKeywords(j, query.Keywords) || !matchLocation(j, query.Location) {
+			continue
+		}
+		offers = append(offers, db.CreateOfferParams{
+			ID:       remoteOKIDPrefix + j.ID,
+			Title:    normalize(j.Position),
+			Company:  normalize(j.Company),
+			Location: normalize(j.Location),
+			PostedAt: pgtype.Timestamptz{Time: time.Unix(j.Epoch, 0), Valid: true},
+			LogoUrl:  j.Logo,
+			Salary:   salary(j),
+			Url:      j.URL,
+			Source:   SourceRemoteOK,
+		})
+	}
+	return offers, nil
+}
+
+func (r *remoteOK) fetchOffers(ctx context.Context) ([]remoteOKJob, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, remoteOKURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", remoteOKUserAgent)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+	resp.Body = limitBody(resp.Body, defaultMaxBodySize)
+
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, errSnippetSize)) //nolint: errcheck
+		if isRetryable[resp.StatusCode] {
+			metrics.ScraperErrors.WithLabelValues(SourceRemoteOK, ReasonRetryable).Inc()
+			return nil, fmt.Errorf("%w: status %d, message: %s", ErrRetryable, resp.StatusCode, snippet)
+		}
+		return nil, fmt.Errorf("received status code: %d, url: %s, message: %s", resp.StatusCode, remoteOKURL, snippet)
+	}
+
+	var jobs []remoteOKJob
+	if err := json.NewDecoder(resp.Body).Decode(&jobs); err != nil {
+		return nil, fmt.Errorf("%w: %w", errDecode, err)
+	}
+	return jobs, nil
+}
+
+// matchKeywords reports whether every keyword is in the job's position or tags.
+func matchKeywords(j remoteOKJob, keywords string) bool {
+	text := strings.ToLower(j.Position + " " + strings.Join(j.Tags, " "))
+	for _, k := range strings.Fields(strings.ToLower(keywords)) {
+		if !strings.Contains(text, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLocation reports whether the job can be done from the location.
+// Jobs without location or open worldwide match every location.
+func matchLocation(j remoteOKJob, location string) bool {
+	l := strings.ToLower(j.Location)
+	loc := strings.ToLower(strings.TrimSpace(location))
+	return l == "" || loc == "remote" || strings.Contains(l, "worldwide") || strings.Contains(l, loc)
+}
+
+func salary(j remoteOKJob) string {
+	switch {
+	case j.SalaryMin > 0 && j.SalaryMax > 0:
+		return fmt.Sprintf("$%d - $%d", j.SalaryMin, j.SalaryMax)
+	case j.SalaryMin > 0:
+		return fmt.Sprintf("$%d", j.SalaryMin)
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/alwedo/jobber/db"
+)
+
+func TestRemoteOKScrape(t *testing.T) {
+	mockResp := &remoteOKMockResp{t: t, status: http.StatusOK}
+	r := RemoteOK()
+	r.client = &http.Client{Transport: mockResp}
+
+	t.Run("maps the offers", func(t *testing.T) {
+		offers, err := r.Scrape(context.Background(), &db.Query{Keywords: "golang", Location: "berlin"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if mockResp.req.Header.Get("User-Agent") == "" {
+			t.Errorf("expected the request to have a User-Agent")
+		}
+		if len(offers) == 0 {
+			t.Fatalf("expected offers, got none")
+		}
+		o := offers[0]
+		if o.ID != "remoteok-1128734" {
+			t.Errorf("expected offer ID 'remoteok-1128734', got '%s'", o.ID)
+		}
+		if o.Source != SourceRemoteOK {
+			t.Errorf("expected offer source '%s', got '%s'", SourceRemoteOK, o.Source)
+		}
+		if o.Title != "Senior Golang Engineer" {
+			t.Errorf("expected offer title 'Senior Golang Engineer', got '%s'", o.Title)
+		}
+		if o.Company != "Acme" {
+			t.Errorf("expected offer company 'Acme', got '%s'", o.Company)
+		}
+		if o.Salary != "$80000 - $110000" {
+			t.Errorf("expected offer salary '$80000 - $110000', got '%s'", o.Salary)
+		}
+		if o.Url != "https://remoteOK.com/remote-jobs/remote-senior-golang-engineer-acme-1128734" {
+			t.Errorf("expected offer url to be RemoteOK's, got '%s'", o.Url)
+		}
+		if o.PostedAt.Time.UTC().Format("2006-01-02") != "2025-11-13" {
+			t.Errorf("expected offer posted at 2025-11-13, got %s", o.PostedAt.Time.UTC().Format("2006-01-02"))
+		}
+	})
+
+	t.Run("retryable status codes return ErrRetryable", func(t *testing.T) {
+		mockResp.status = http.StatusTooManyRequests
+		defer func() { mockResp.status = http.StatusOK }()
+		_, err := r.Scrape(context.Background(), &db.Query{Keywords: "golang", Location: "berlin"})
+		if !errors.Is(err, ErrRetryable) {
+			t.Errorf("expected ErrRetryable, got %v", err)
+		}
+	})
+}
+
+func TestRemoteOKFilter(t *testing.T) {
+	r := RemoteOK()
+	r.client = &http.Client{Transport: &remoteOKMockResp{t: t, status: http.StatusOK}}
+
+	tests := []struct {
+		name     string
+		keywords string
+		location string
+		wantIDs  []string
+	}{
+		{
+			name:     "keywords match position or tags",
+			keywords: "golang",
+			location: "berlin",
+			wantIDs:  []string{"remoteok-1128734", "remoteok-1128730"},
+		},
+		{
+			name:     "all keywords must match",
+			keywords: "senior golang",
+			location: "berlin",
+			wantIDs:  []string{"remoteok-1128734"},
+		},
+		{
+			name:     "offers without location match any location",
+			keywords: "rust",
+			location: "munich",
+			wantIDs:  []string{"remoteok-1128720"},
+		},
+		{
+			name:     "remote location matches every offer",
+			keywords: "golang",
+			location: "remote",
+			wantIDs:  []string{"remoteok-1128734", "remoteok-1128730", "remoteok-1128725"},
+		},
+		{
+			name:     "no matches",
+			keywords: "cobol",
+			location: "berlin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offers, err := r.Scrape(context.Background(), &db.Query{Keywords: tt.keywords, Location: tt.location})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			var gotIDs []string
+			for _, o := range offers {
+				gotIDs = append(gotIDs, o.ID)
+			}
+			if !slices.Equal(tt.wantIDs, gotIDs) {
+				t.Errorf("expected offers %v, got %v", tt.wantIDs, gotIDs)
+			}
+		})
+	}
+}
+
+type remoteOKMockResp struct {
+	t      testing.TB
+	req    *http.Request
+	status int
+}
+
+func (h *remoteOKMockResp) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.req = req
+	body, err := os.Open("test_data/remoteok.json")
+	if err != nil {
+		h.t.Fatalf("failed to open test_data/remoteok.json in remoteOKMockResp.RoundTrip: %s", err)
+	}
+	return &http.Response{
+		StatusCode: h.status,
+		Body:       body,
+	}, nil
+}
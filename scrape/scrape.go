@@ -6,6 +6,8 @@ package scrape
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/alwedo/jobber/db"
@@ -13,9 +15,106 @@ import (
 
 type Scraper interface {
 	Scrape(context.Context, *db.Query) ([]db.CreateOfferParams, error)
+	// Name returns the scraped portal's name, used to label metrics and logs.
+	Name() string
 }
 
-var ErrRetryable = errors.New("scrape: retryable error")
+// Debugger is a Scraper telling how its parsing went, to debug
+// scrapes finding fewer offers than expected.
+type Debugger interface {
+	ScrapeDebug(context.Context, *db.Query) ([]db.CreateOfferParams, *DebugInfo, error)
+}
+
+// Sources identify the job portal an offer was scraped from. Offer IDs
+// are only unique within their source.
+const (
+	SourceLinkedIn = "linkedin"
+	SourceRemoteOK = "remoteok"
+)
+
+// Time posted ranges of the offers a query's first scrape looks for.
+// Later scrapes only look for the offers posted since the previous one.
+const (
+	TimePosted24h   = "24h"
+	TimePostedWeek  = "week" // The default.
+	TimePostedMonth = "month"
+	TimePostedAll   = "all"
+)
+
+// timePostedSeconds are the time posted ranges in seconds, zero being any time.
+var timePostedSeconds = map[string]int{
+	TimePosted24h:   24 * 60 * 60,
+	TimePostedWeek:  7 * 24 * 60 * 60,
+	TimePostedMonth: 30 * 24 * 60 * 60,
+	TimePostedAll:   0,
+}
+
+// ValidTimePostedRange reports whether r is one of the time posted ranges.
+func ValidTimePostedRange(r string) bool {
+	_, ok := timePostedSeconds[r]
+	return ok
+}
+
+var (
+	ErrRetryable    = errors.New("scrape: retryable error")
+	ErrBodyTooLarge = errors.New("scrape: response body too large")
+)
+
+// defaultMaxBodySize bounds the response bodies read from the job portals,
+// so a malfunctioning one can't make us run out of memory.
+const defaultMaxBodySize = 10 << 20 // 10MB
+
+// limitedBody is a response body that fails with ErrBodyTooLarge
+// once more than max bytes are read from it.
+type limitedBody struct {
+	io.ReadCloser
+	max, remaining int64
+}
+
+func limitBody(body io.ReadCloser, maxSize int64) io.ReadCloser {
+	return &limitedBody{ReadCloser: body, max: maxSize, remaining: maxSize}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	// We read one byte past the limit to know if it's exceeded.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, b.max)
+	}
+	return n, err
+}
+
+// Reasons a scrape fails for, used to label the scrape errors metric.
+const (
+	ReasonRetryable = "retryable" // A retryable response, counted on every retry.
+	ReasonExhausted = "exhausted" // Retries were exhausted.
+	ReasonHTTP      = "http"
+	ReasonParse     = "parse"
+)
+
+// Error is a failed scrape, with the reason it failed for.
+type Error struct {
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// fetchError wraps an error fetching a portal's offers, telling
+// exhausted retries apart from the rest of the HTTP errors.
+func fetchError(err error) error {
+	reason := ReasonHTTP
+	if errors.Is(err, ErrRetryable) {
+		reason = ReasonExhausted
+	}
+	return &Error{Reason: reason, Err: err}
+}
 
 var isRetryable = map[int]bool{
 	http.StatusRequestTimeout:      true,
@@ -36,4 +135,6 @@ func (m *mockScraper) Scrape(_ context.Context, q *db.Query) ([]db.CreateOfferPa
 	return []db.CreateOfferParams{}, nil
 }
 
+func (m *mockScraper) Name() string { return "mock" }
+
 var MockScraper = &mockScraper{}
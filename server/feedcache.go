@@ -0,0 +1,114 @@
+package server
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alwedo/jobber/metrics"
+)
+
+const (
+	defaultFeedCacheTTL = time.Minute
+	feedCacheMaxItems   = 1000
+)
+
+// WithFeedCacheTTL sets how long the rendered feeds are served from memory,
+// so readers polling them all at once don't hit the DB every time.
+// Defaults to 1 minute, 0 disables the cache.
+func WithFeedCacheTTL(d time.Duration) Option {
+	return func(s *server) {
+		s.feedCacheTTL = d
+	}
+}
+
+type cachedFeed struct {
+	key          string
+	contentType  string
+	body         []byte
+	etag         string
+	lastModified time.Time
+	expires      time.Time
+	// touched is set once a hit updated the query's queried_at,
+	// so a polling storm updates it once per cached feed.
+	touched bool
+}
+
+// feedCache is a size bounded LRU cache of the rendered feeds, which
+// expire after the TTL. Feeds whose query is gone or still gathering
+// aren't cached, as they're about to change.
+type feedCache struct {
+	ttl time.Duration
+	max int
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	lru   *list.List
+}
+
+func newFeedCache(ttl time.Duration) *feedCache {
+	return &feedCache{
+		ttl:   ttl,
+		max:   feedCacheMaxItems,
+		items: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+// feedCacheKey identifies a rendered feed. The host is part of it, as
+// the feeds link back to it, and the params are encoded sorted by key.
+func feedCacheKey(r *http.Request) string {
+	return r.Host + "?" + r.URL.Query().Encode()
+}
+
+// get returns the cached feed, if it hasn't expired, and whether
+// it's the first hit needing to update the query's queried_at.
+func (c *feedCache) get(key string) (f cachedFeed, touch, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return cachedFeed{}, false, false
+	}
+	cf := e.Value.(*cachedFeed)
+	if time.Now().After(cf.expires) {
+		c.remove(e)
+		return cachedFeed{}, false, false
+	}
+	c.lru.MoveToFront(e)
+	touch = !cf.touched
+	cf.touched = true
+	return *cf, touch, true
+}
+
+// store caches the feed, evicting the least recently used ones.
+func (c *feedCache) store(key string, f cachedFeed) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.remove(e)
+	}
+	f.key = key
+	f.expires = time.Now().Add(c.ttl)
+	f.touched = false
+	c.items[key] = c.lru.PushFront(&f)
+	for c.lru.Len() > c.max {
+		c.remove(c.lru.Back())
+	}
+	metrics.CacheItems.WithLabelValues("feeds").Set(float64(c.lru.Len()))
+}
+
+// clear drops all the cached feeds, ie. after a query is deleted.
+func (c *feedCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.items)
+	c.lru.Init()
+	metrics.CacheItems.WithLabelValues("feeds").Set(0)
+}
+
+func (c *feedCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.items, e.Value.(*cachedFeed).key)
+}
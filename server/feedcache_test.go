@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestFeedCache(t *testing.T) {
+	t.Run("feeds expire after the ttl", func(t *testing.T) {
+		synctest.Test(t, func(t *testing.T) {
+			c := newFeedCache(time.Minute)
+			c.store("feed", cachedFeed{contentType: "application/rss+xml", body: []byte("<rss/>")})
+
+			f, touch, ok := c.get("feed")
+			if !ok || string(f.body) != "<rss/>" || f.contentType != "application/rss+xml" {
+				t.Fatalf("wanted the cached feed, got %+v (found: %t)", f, ok)
+			}
+			if !touch {
+				t.Error("wanted the first hit to touch the query")
+			}
+			if _, touch, _ := c.get("feed"); touch {
+				t.Error("wanted the next hits not to touch the query again")
+			}
+
+			time.Sleep(time.Minute + time.Second)
+			if _, _, ok := c.get("feed"); ok {
+				t.Error("wanted the feed to expire")
+			}
+		})
+	})
+
+	t.Run("least recently used feeds are evicted", func(t *testing.T) {
+		c := newFeedCache(time.Minute)
+		c.max = 2
+		c.store("a", cachedFeed{})
+		c.store("b", cachedFeed{})
+		c.get("a")
+		c.store("c", cachedFeed{})
+		for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+			if _, _, ok := c.get(key); ok != want {
+				t.Errorf("wanted feed %s cached to be %t, got %t", key, want, ok)
+			}
+		}
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		c := newFeedCache(time.Minute)
+		for i := range 3 {
+			c.store(fmt.Sprint(i), cachedFeed{})
+		}
+		c.clear()
+		if c.lru.Len() != 0 || len(c.items) != 0 {
+			t.Errorf("wanted an empty cache, got %d feeds", c.lru.Len())
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		a := httptest.NewRequest(http.MethodGet, "http://jobber.example/feeds?keywords=golang&location=berlin", nil)
+		b := httptest.NewRequest(http.MethodGet, "http://jobber.example/feeds?location=berlin&keywords=golang", nil)
+		if feedCacheKey(a) != feedCacheKey(b) {
+			t.Errorf("wanted the params' order not to matter, got %q and %q", feedCacheKey(a), feedCacheKey(b))
+		}
+		c := httptest.NewRequest(http.MethodGet, "http://other.example/feeds?keywords=golang&location=berlin", nil)
+		if feedCacheKey(a) == feedCacheKey(c) {
+			t.Errorf("wanted the host to matter, got %q for both", feedCacheKey(a))
+		}
+		d := httptest.NewRequest(http.MethodGet, "http://jobber.example/feeds?keywords=golang&location=berlin&format=json", nil)
+		if feedCacheKey(a) == feedCacheKey(d) {
+			t.Errorf("wanted the format to matter, got %q for both", feedCacheKey(a))
+		}
+	})
+}
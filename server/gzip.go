@@ -0,0 +1,138 @@
+package server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipMinSize is the smallest response worth compressing,
+// smaller ones can grow with gzip's overhead.
+const gzipMinSize = 1024
+
+// gzipped compresses the handler's responses for the clients accepting gzip,
+// once they're at least gzipMinSize bytes. Only use it for text responses.
+func gzipped(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next(w, r)
+			return
+		}
+		gw := &gzipWriter{ResponseWriter: w}
+		defer gw.close()
+		next(gw, r)
+	}
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows gzip.
+func acceptsGzip(r *http.Request) bool {
+	for e := range strings.SplitSeq(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(e, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// gzip;q=0 means the client refuses it.
+		name, value, _ := strings.Cut(strings.TrimSpace(params), "=")
+		if strings.EqualFold(strings.TrimSpace(name), "q") {
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			return err == nil && q > 0
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter buffers the start of the response until it knows whether it's
+// large enough to compress it. Then it writes the headers and the body,
+// either compressed or as they are.
+type gzipWriter struct {
+	http.ResponseWriter
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+	// Responses without body, like 304 Not Modified, aren't buffered.
+	if code == http.StatusNotModified || code == http.StatusNoContent {
+		w.start(false)
+	}
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	if w.decided {
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= gzipMinSize {
+		if err := w.start(w.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// compressible reports whether the response can be compressed. Partial and
+// already encoded responses are left as they are.
+func (w *gzipWriter) compressible() bool {
+	h := w.Header()
+	return (w.status == 0 || w.status == http.StatusOK) &&
+		h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == ""
+}
+
+// start writes the headers and the buffered body, compressed or not.
+func (w *gzipWriter) start(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if compress {
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		// The compressed body isn't byte for byte the one the ETag validates,
+		// conditional requests still match it as a weak one.
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close writes the responses smaller than gzipMinSize, or ends the compressed one.
+func (w *gzipWriter) close() {
+	if !w.decided {
+		w.start(false) //nolint: errcheck // The client is gone, nothing else to do.
+	}
+	if w.gz != nil {
+		w.gz.Close() //nolint: errcheck // The client is gone, nothing else to do.
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	svr, err := New(l, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{name: "gzip accepted", path: "/help", acceptEncoding: "gzip", wantGzip: true},
+		{name: "gzip among others", path: "/help", acceptEncoding: "br;q=1.0, gzip;q=0.8, *;q=0.1", wantGzip: true},
+		{name: "gzip refused", path: "/help", acceptEncoding: "gzip;q=0, identity", wantGzip: false},
+		{name: "gzip not accepted", path: "/help", acceptEncoding: "identity", wantGzip: false},
+		// Requests without params get a short error message.
+		{name: "small response", path: "/feeds", acceptEncoding: "gzip", wantGzip: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			// Setting it explicitly stops the client from decompressing the body.
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			defer r.Body.Close()
+
+			if got := r.Header.Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Fatalf("wanted gzip encoding to be %t, got Content-Encoding %q", tt.wantGzip, r.Header.Get("Content-Encoding"))
+			}
+			if !strings.Contains(r.Header.Get("Vary"), "Accept-Encoding") {
+				t.Errorf("wanted Vary to include Accept-Encoding, got %q", r.Header.Get("Vary"))
+			}
+			body := io.Reader(r.Body)
+			if tt.wantGzip {
+				gz, err := gzip.NewReader(r.Body)
+				if err != nil {
+					t.Fatalf("unable to read the gzip stream: %v", err)
+				}
+				body = gz
+			}
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("unable to read the body: %v", err)
+			}
+			if tt.path == "/help" {
+				if !strings.Contains(string(b), "</html>") {
+					t.Errorf("wanted the whole help page, got %q", b)
+				}
+				if ct := r.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+					t.Errorf("wanted an html Content-Type, got %q", ct)
+				}
+			}
+		})
+	}
+}
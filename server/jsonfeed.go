@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// jsonFeed is a JSON Feed 1.1 document, see https://www.jsonfeed.org/version/1.1/
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentText   string           `json:"content_text,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// writeJSONFeed renders the feed data as a JSON Feed document.
+func writeJSONFeed(w io.Writer, d *feedData) error {
+	home := "https://" + d.Host
+	qp := url.Values{}
+	qp.Add(queryParamKeywords, d.Keywords)
+	qp.Add(queryParamLocation, d.Location)
+	qp.Add(queryParamFormat, formatJSON)
+
+	f := &jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       fmt.Sprintf("%s jobs in %s", d.Keywords, d.Location),
+		HomePageURL: home,
+		FeedURL:     home + "/feeds?" + qp.Encode(),
+		Items:       []jsonFeedItem{},
+	}
+	if d.NotFound {
+		f.Title = "feed not found :("
+		f.Description = fmt.Sprintf("no feed has been found for %s jobs in %s", d.Keywords, d.Location)
+		f.Items = append(f.Items, jsonFeedItem{
+			ID:            "1",
+			URL:           home,
+			Title:         fmt.Sprintf("no query has been found for %s jobs in %s", d.Keywords, d.Location),
+			ContentText:   "try creating a new feed at " + home,
+			DatePublished: time.Now().Format(time.RFC3339),
+		})
+	}
+	if d.Gathering {
+		f.Items = append(f.Items, jsonFeedItem{
+			ID:            "gathering",
+			URL:           home,
+			Title:         "we're still collecting jobs, check back in a few minutes",
+			DatePublished: time.Now().Format(time.RFC3339),
+		})
+	}
+	if !d.FailedAt.IsZero() {
+		f.Items = append(f.Items, jsonFeedItem{
+			ID:            "failing",
+			URL:           home,
+			Title:         "we're having trouble collecting jobs, this feed might be missing the newest ones",
+			DatePublished: d.FailedAt.Format(time.RFC3339),
+		})
+	}
+	for _, o := range d.Offers {
+		f.Items = append(f.Items, jsonFeedItem{
+			ID:            o.ID,
+			URL:           offerLink(o),
+			Title:         fmt.Sprintf("%s at %s", o.Title, o.Company),
+			DatePublished: o.PostedAt.Time.Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: o.Company}},
+		})
+	}
+	return json.NewEncoder(w).Encode(f)
+}
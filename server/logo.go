@@ -0,0 +1,179 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alwedo/jobber/metrics"
+)
+
+const (
+	logoMaxSize      = 256 << 10 // Max size of a single logo, 256KB.
+	logoMaxCacheSize = 32 << 20  // Max size of all the cached logos, 32MB.
+	logoFetchTimeout = 5 * time.Second
+	logoMaxAge       = 7 * 24 * time.Hour // Offers are kept for 7 days, so are their logos.
+)
+
+var (
+	errLogoNotFound = errors.New("logo not found")
+	errLogoTooLarge = errors.New("logo too large")
+	errLogoHost     = errors.New("logo host not allowed")
+)
+
+// logoHosts are the job portals' CDNs serving the logos, along with their subdomains.
+// The logo URLs come from the scraped offers, so we don't fetch them anywhere else.
+var logoHosts = []string{"media.licdn.com", "remoteok.com", "remoteok.io"}
+
+// offerKey identifies an offer, as IDs are only unique within their source.
+type offerKey struct {
+	source, id string
+}
+
+type logo struct {
+	offer       offerKey
+	contentType string
+	body        []byte
+}
+
+// logoProxy fetches the offers' company logos from the job portals and keeps
+// them in a size bounded LRU cache, so RSS readers get the images from us
+// instead of hotlinking the portal's CDN and leaking their IPs to it.
+type logoProxy struct {
+	client *http.Client
+	// logoURL returns the original logo URL of an offer.
+	logoURL  func(ctx context.Context, offer offerKey) (string, error)
+	hosts    []string
+	maxSize  int64
+	maxCache int64
+
+	mu    sync.Mutex
+	size  int64
+	items map[offerKey]*list.Element
+	lru   *list.List
+}
+
+func newLogoProxy(logoURL func(ctx context.Context, offer offerKey) (string, error)) *logoProxy {
+	p := &logoProxy{
+		logoURL:  logoURL,
+		hosts:    logoHosts,
+		maxSize:  logoMaxSize,
+		maxCache: logoMaxCacheSize,
+		items:    make(map[offerKey]*list.Element),
+		lru:      list.New(),
+	}
+	p.client = &http.Client{
+		Timeout: logoFetchTimeout,
+		// Redirects can't leave the allowed hosts either.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !p.allowed(req.URL) {
+				return fmt.Errorf("%w: redirected to %s", errLogoHost, req.URL.Host)
+			}
+			return nil
+		},
+	}
+	return p
+}
+
+// allowed returns whether the logo URL is on one of the allowed hosts.
+func (p *logoProxy) allowed(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, h := range p.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the logo of an offer, fetching it if it's not cached.
+func (p *logoProxy) get(ctx context.Context, offer offerKey) (*logo, error) {
+	if l, ok := p.cached(offer); ok {
+		return l, nil
+	}
+	u, err := p.logoURL(ctx, offer)
+	if err != nil {
+		return nil, err
+	}
+	if u == "" {
+		return nil, errLogoNotFound
+	}
+	l, err := p.fetch(ctx, offer, u)
+	if err != nil {
+		return nil, err
+	}
+	p.store(l)
+	return l, nil
+}
+
+func (p *logoProxy) fetch(ctx context.Context, offer offerKey, u string) (*logo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if !p.allowed(req.URL) {
+		return nil, fmt.Errorf("%w: %s", errLogoHost, req.URL.Host)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch logo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code: %d, url: %s", resp.StatusCode, u)
+	}
+	ct := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "image/") {
+		return nil, fmt.Errorf("unexpected content type: %q, url: %s", ct, u)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response body: %w", err)
+	}
+	if int64(len(body)) > p.maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes, url: %s", errLogoTooLarge, p.maxSize, u)
+	}
+	return &logo{offer: offer, contentType: ct, body: body}, nil
+}
+
+func (p *logoProxy) cached(offer offerKey) (*logo, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.items[offer]
+	if !ok {
+		return nil, false
+	}
+	p.lru.MoveToFront(e)
+	return e.Value.(*logo), true
+}
+
+// store adds the logo to the cache, evicting the least
+// recently used ones until the cache fits its max size.
+func (p *logoProxy) store(l *logo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.items[l.offer]; ok {
+		return
+	}
+	p.items[l.offer] = p.lru.PushFront(l)
+	p.size += int64(len(l.body))
+	for p.size > p.maxCache {
+		e := p.lru.Back()
+		old := e.Value.(*logo)
+		p.lru.Remove(e)
+		delete(p.items, old.offer)
+		p.size -= int64(len(old.body))
+	}
+	metrics.CacheItems.WithLabelValues("logos").Set(float64(p.lru.Len()))
+}
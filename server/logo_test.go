@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alwedo/jobber/jobber"
+	"github.com/alwedo/jobber/scrape"
+)
+
+func TestLogoProxy(t *testing.T) {
+	img := bytes.Repeat([]byte{0xFF}, 1024)
+	var hits atomic.Int32
+	imgServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/redirect.png" {
+			// localhost is the same server, but not an allowed host.
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/logo.png", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/huge.png" {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(bytes.Repeat([]byte{0xFF}, logoMaxSize+1)) //nolint: errcheck
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img) //nolint: errcheck
+	}))
+	defer imgServer.Close()
+
+	logos := map[offerKey]string{
+		{scrape.SourceLinkedIn, "offer_001"}:  imgServer.URL + "/logo.png",
+		{scrape.SourceLinkedIn, "huge"}:       imgServer.URL + "/huge.png",
+		{scrape.SourceLinkedIn, "no_logo"}:    "",
+		{scrape.SourceLinkedIn, "other_host"}: strings.Replace(imgServer.URL, "127.0.0.1", "localhost", 1) + "/logo.png",
+		{scrape.SourceLinkedIn, "redirected"}: imgServer.URL + "/redirect.png",
+		{scrape.SourceRemoteOK, "remote_001"}: imgServer.URL + "/logo.png",
+	}
+	s := &server{
+		logger: slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{})),
+		logos: newLogoProxy(func(_ context.Context, offer offerKey) (string, error) {
+			u, ok := logos[offer]
+			if !ok {
+				return "", jobber.ErrOfferNotFound
+			}
+			return u, nil
+		}),
+	}
+	// The image server stands for the portals' CDNs.
+	s.logos.hosts = []string{"127.0.0.1"}
+	svr := httptest.NewServer(s.img())
+	defer svr.Close()
+
+	get := func(t *testing.T, offer string) *http.Response {
+		t.Helper()
+		r, err := http.Get(svr.URL + "/img?offer=" + offer)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		return r
+	}
+
+	t.Run("serves and caches the logo", func(t *testing.T) {
+		for range 2 {
+			r := get(t, "offer_001")
+			if r.StatusCode != http.StatusOK {
+				t.Errorf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+			}
+			if ct := r.Header.Get("Content-Type"); ct != "image/png" {
+				t.Errorf("wanted content type image/png, got %s", ct)
+			}
+			if r.Header.Get("Cache-Control") == "" {
+				t.Errorf("wanted Cache-Control header to be set")
+			}
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				t.Errorf("unable to read response body: %v", err)
+			}
+			if !bytes.Equal(body, img) {
+				t.Errorf("wanted the proxied image body, got %d bytes", len(body))
+			}
+		}
+		if hits.Load() != 1 {
+			t.Errorf("wanted the image server to be hit once, got %d", hits.Load())
+		}
+	})
+
+	t.Run("unknown offer or offer without logo returns 404", func(t *testing.T) {
+		for _, o := range []string{"cuak", "no_logo"} {
+			r := get(t, o)
+			r.Body.Close()
+			if r.StatusCode != http.StatusNotFound {
+				t.Errorf("wanted status code %d for %s, got %d", http.StatusNotFound, o, r.StatusCode)
+			}
+		}
+	})
+
+	t.Run("logos bigger than the max size are rejected", func(t *testing.T) {
+		r := get(t, "huge")
+		r.Body.Close()
+		if r.StatusCode != http.StatusBadGateway {
+			t.Errorf("wanted status code %d, got %d", http.StatusBadGateway, r.StatusCode)
+		}
+	})
+
+	t.Run("logos are only fetched from the allowed hosts", func(t *testing.T) {
+		before := hits.Load()
+		r := get(t, "other_host")
+		r.Body.Close()
+		if r.StatusCode != http.StatusBadGateway {
+			t.Errorf("wanted status code %d, got %d", http.StatusBadGateway, r.StatusCode)
+		}
+		if hits.Load() != before {
+			t.Errorf("wanted the image server not to be hit")
+		}
+	})
+
+	t.Run("redirects to other hosts are rejected", func(t *testing.T) {
+		before := hits.Load()
+		r := get(t, "redirected")
+		r.Body.Close()
+		if r.StatusCode != http.StatusBadGateway {
+			t.Errorf("wanted status code %d, got %d", http.StatusBadGateway, r.StatusCode)
+		}
+		if hits.Load() != before+1 {
+			t.Errorf("wanted only the redirect to be hit, got %d hits", hits.Load()-before)
+		}
+	})
+
+	t.Run("allowed hosts include their subdomains", func(t *testing.T) {
+		p := newLogoProxy(nil)
+		for u, want := range map[string]bool{
+			"https://media.licdn.com/dms/image/logo.png": true,
+			"https://remoteok.com/assets/img/logo.png":   true,
+			"https://cdn.remoteok.com/logo.png":          true,
+			"https://MEDIA.LICDN.COM/logo.png":           true,
+			"https://notremoteok.com/logo.png":           false,
+			"https://remoteok.com.evil.com/logo.png":     false,
+			"http://169.254.169.254/latest/meta-data":    false,
+		} {
+			parsed, err := url.Parse(u)
+			if err != nil {
+				t.Fatalf("unable to parse %s: %v", u, err)
+			}
+			if got := p.allowed(parsed); got != want {
+				t.Errorf("wanted %s allowed to be %t, got %t", u, want, got)
+			}
+		}
+	})
+
+	t.Run("offers are looked up by source", func(t *testing.T) {
+		for offer, want := range map[string]int{
+			"remote_001&source=remoteok": http.StatusOK,
+			"offer_001&source=remoteok":  http.StatusNotFound,
+			"remote_001":                 http.StatusNotFound, // Defaults to LinkedIn.
+		} {
+			r := get(t, offer)
+			r.Body.Close()
+			if r.StatusCode != want {
+				t.Errorf("wanted status code %d for %s, got %d", want, offer, r.StatusCode)
+			}
+		}
+	})
+
+	t.Run("cache evicts least recently used logos", func(t *testing.T) {
+		p := newLogoProxy(nil)
+		p.maxCache = 2 * int64(len(img))
+		for _, id := range []string{"a", "b", "c"} {
+			p.store(&logo{offer: offerKey{id: id}, body: img})
+		}
+		if _, ok := p.cached(offerKey{id: "a"}); ok {
+			t.Errorf("wanted logo 'a' to be evicted")
+		}
+		if _, ok := p.cached(offerKey{id: "c"}); !ok {
+			t.Errorf("wanted logo 'c' to be cached")
+		}
+		if p.size > p.maxCache {
+			t.Errorf("wanted cache size to be at most %d, got %d", p.maxCache, p.size)
+		}
+	})
+}
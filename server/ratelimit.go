@@ -0,0 +1,105 @@
+package server
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRateLimit = 20 // Requests per minute per client.
+	// rateLimitSweep is how often the buckets of idle clients are dropped.
+	rateLimitSweep = time.Minute
+)
+
+// WithRateLimit sets how many requests per minute each client IP can
+// make to the endpoints creating and deleting feeds. Defaults to 20,
+// zero disables the rate limit.
+func WithRateLimit(perMinute int) Option {
+	return func(s *server) {
+		s.rateLimit = perMinute
+	}
+}
+
+// rateLimiter is a token bucket per client, refilled at rate tokens per
+// second up to burst, where each request takes a token.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		rate:      float64(perMinute) / 60,
+		burst:     float64(perMinute),
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether the client has a token left, taking it.
+func (l *rateLimiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep drops the buckets refilled by now, as they're
+// the same as new ones, so the map doesn't grow forever.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweep {
+		return
+	}
+	l.lastSweep = now
+	for c, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, c)
+		}
+	}
+}
+
+// limit responds with 429 to the clients exceeding the rate limit.
+func (s *server) limit(next http.HandlerFunc) http.HandlerFunc {
+	if s.limiter == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if !s.limiter.allow(client) {
+			s.logger.Info("rate limit exceeded", slog.String("client", client), slog.String("path", r.URL.Path))
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	const perMinute = 3
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	// Requests without params are rejected before reaching the jobber.
+	svr, err := New(l, nil, WithRateLimit(perMinute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	do := func(method string) int {
+		req, err := http.NewRequest(method, server.URL+"/feeds", nil)
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		r.Body.Close()
+		return r.StatusCode
+	}
+
+	for i := range perMinute {
+		if got := do(http.MethodPost); got != http.StatusBadRequest {
+			t.Errorf("wanted request %d to pass the rate limit, got status code %d", i+1, got)
+		}
+	}
+	if got := do(http.MethodPost); got != http.StatusTooManyRequests {
+		t.Errorf("wanted status code %d once the rate limit is exceeded, got %d", http.StatusTooManyRequests, got)
+	}
+	if got := do(http.MethodDelete); got != http.StatusTooManyRequests {
+		t.Errorf("wanted deletes to share the rate limit, got status code %d", got)
+	}
+	if got := do(http.MethodGet); got != http.StatusBadRequest {
+		t.Errorf("wanted feed reads not to be rate limited, got status code %d", got)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(60) // A token per second.
+	l.now = func() time.Time { return now }
+
+	for range 60 {
+		if !l.allow("1.1.1.1") {
+			t.Fatal("wanted requests within the burst to be allowed")
+		}
+	}
+	if l.allow("1.1.1.1") {
+		t.Error("wanted the request exceeding the burst to be denied")
+	}
+	if !l.allow("2.2.2.2") {
+		t.Error("wanted other clients not to be limited")
+	}
+
+	now = now.Add(time.Second)
+	if !l.allow("1.1.1.1") {
+		t.Error("wanted a request to be allowed after a token is refilled")
+	}
+	if l.allow("1.1.1.1") {
+		t.Error("wanted a single token to be refilled after a second")
+	}
+
+	now = now.Add(rateLimitSweep)
+	l.allow("3.3.3.3")
+	if _, ok := l.buckets["2.2.2.2"]; ok {
+		t.Error("wanted the refilled buckets to be swept")
+	}
+}
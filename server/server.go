@@ -1,21 +1,33 @@
 package server
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"embed"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html"
+	"io/fs"
 	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/alwedo/jobber/db"
 	"github.com/alwedo/jobber/jobber"
 	"github.com/alwedo/jobber/metrics"
+	"github.com/alwedo/jobber/scrape"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -23,13 +35,68 @@ const (
 	// Params.
 	queryParamKeywords = "keywords"
 	queryParamLocation = "location"
+	queryParamOffer    = "offer"
+	queryParamSource   = "source" // Offer's job portal, LinkedIn when missing.
+	queryParamFormat   = "format"
+	queryParamInterval = "interval" // Hours between the query's scrapes.
+	queryParamSince    = "since"    // Time posted range of the query's first scrape.
+	queryParamRemote   = "remote"   // Scrapes only remote offers when "true".
+	queryParamGeoID    = "geo_id"   // LinkedIn's ID of the location, see jobber.CreateQuery.
+	queryParamQuery    = "q"        // Combined feeds' keywords|location pairs, or a search.
+	queryParamGroup    = "group"    // Query group's ID, see server.createGroup.
+	queryParamLimit    = "limit"    // Max offers in a feed, capped by jobber.MaxOffersLimit.
+	queryParamOffset   = "offset"   // Offers skipped, to paginate a feed.
+	queryParamMaxAge   = "max_age"  // Max age of a feed's offers, ie. "24h" or "3d".
+	// Feeds' company filters, which can be repeated.
+	queryParamCompany        = "company"
+	queryParamExcludeCompany = "exclude_company"
+
+	// Notification params.
+	queryParamWebhook          = "webhook"
+	queryParamRemoteOnly       = "remote_only"
+	queryParamNewCompaniesOnly = "new_companies_only"
+	queryParamMinSalary        = "min_salary"
+
+	// Feed formats.
+	formatRSS  = "rss"
+	formatAtom = "atom"
+	formatJSON = "json"
+
+	// Count formats.
+	formatText    = "text"
+	formatShields = "shields" // shields.io endpoint badge.
 
 	// Assets.
 	assetsGlob          = "assets/*"
 	assetIndex          = "index.gohtml"
 	assetHelp           = "help.gohtml"
 	assetRSS            = "rss.goxml"
+	assetAtom           = "atom.goxml"
 	assetCreateResponse = "create_response.gohtml"
+	assetOffersFragment = "offers_fragment.gohtml"
+
+	// gatheringMaxAge is how long readers should cache a feed
+	// whose initial scrape is still running before re-polling.
+	gatheringMaxAge = time.Minute
+
+	defaultDBTimeout = 2 * time.Second
+
+	// HTTP server's timeouts.
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 15 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+
+	// headerAdminSecret authorizes the admin endpoints' requests.
+	headerAdminSecret = "X-Admin-Secret"
+	// headerAPIKey authorizes creating and deleting feeds, as an alternative to a bearer token.
+	headerAPIKey = "X-API-Key"
+
+	// scrapeDebugTimeout bounds the admin's debug scrapes, within the write timeout.
+	scrapeDebugTimeout = 20 * time.Second
+
+	// maxCombinedQueries bounds the queries merged in a combined feed.
+	maxCombinedQueries = 10
 )
 
 //go:embed assets/*
@@ -39,35 +106,242 @@ type server struct {
 	logger    *slog.Logger
 	jobber    *jobber.Jobber
 	templates *template.Template
+	logos     *logoProxy
+	imageURL  string
+	dbTimeout time.Duration
+	sourceURL func(query *db.Query) string
+	ping      func(context.Context) error
+	// popularFeeds is how many popular feeds the index lists, none when zero.
+	popularFeeds int
+	rateLimit    int
+	limiter      *rateLimiter
+	adminSecret  string
+	apiKey       string
+	timeouts     Timeouts
+	feedCacheTTL time.Duration
+	feeds        *feedCache
+}
+
+// Timeouts are the HTTP server's timeouts, see http.Server for their meaning.
+// Zero values keep the defaults.
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+type Option func(*server)
+
+// WithLogoProxy enables the /img endpoint, which serves the offers' company
+// logos through the server instead of linking to the job portal's CDN.
+func WithLogoProxy() Option {
+	return func(s *server) {
+		s.logos = newLogoProxy(func(ctx context.Context, offer offerKey) (string, error) {
+			o, err := s.jobber.GetOffer(ctx, offer.source, offer.id)
+			if err != nil {
+				return "", err
+			}
+			return o.LogoUrl, nil
+		})
+	}
+}
+
+// WithImageURL sets the image shown by feed readers for the RSS channel.
+func WithImageURL(u string) Option {
+	return func(s *server) {
+		s.imageURL = u
+	}
+}
+
+// WithSourceURL includes in the feeds the job portal's search URL scraped for them,
+// so users can verify what's being scraped. It's off by default as it exposes internals.
+func WithSourceURL() Option {
+	return func(s *server) {
+		s.sourceURL = scrape.LinkedInSearchURL
+	}
+}
+
+// WithPing sets the function /readyz calls to check the DB connectivity.
+// Without it, the server is ready as soon as it's up.
+func WithPing(ping func(context.Context) error) Option {
+	return func(s *server) {
+		s.ping = ping
+	}
+}
+
+// WithPopularFeeds lists the n most recently read feeds on the index page, so
+// new users can discover existing ones. It's off by default as it discloses
+// what others are searching for.
+func WithPopularFeeds(n int) Option {
+	return func(s *server) {
+		s.popularFeeds = n
+	}
+}
+
+// WithAdminSecret enables the admin endpoints, like POST /feeds/refresh,
+// authorized by sending the secret in the X-Admin-Secret header.
+func WithAdminSecret(secret string) Option {
+	return func(s *server) {
+		s.adminSecret = secret
+	}
 }
 
-func New(l *slog.Logger, j *jobber.Jobber) (*http.Server, error) {
-	t, err := template.New("").Funcs(funcMap).ParseFS(assets, assetsGlob)
+// WithAPIKey requires the requests creating and deleting feeds, and setting
+// their notifications, to send the key, either as an "Authorization: Bearer"
+// token or in the X-API-Key header.
+// Feed reads stay open.
+func WithAPIKey(key string) Option {
+	return func(s *server) {
+		s.apiKey = key
+	}
+}
+
+// WithDBTimeout sets the deadline of each DB operation performed
+// while handling a request. Defaults to 2 seconds.
+func WithDBTimeout(d time.Duration) Option {
+	return func(s *server) {
+		s.dbTimeout = d
+	}
+}
+
+// WithTimeouts overrides the HTTP server's timeouts, which default to
+// 10s to read the headers, 15s to read the request, 30s to write the
+// response and 120s for idle keep-alive connections.
+func WithTimeouts(t Timeouts) Option {
+	return func(s *server) {
+		if t.ReadHeader > 0 {
+			s.timeouts.ReadHeader = t.ReadHeader
+		}
+		if t.Read > 0 {
+			s.timeouts.Read = t.Read
+		}
+		if t.Write > 0 {
+			s.timeouts.Write = t.Write
+		}
+		if t.Idle > 0 {
+			s.timeouts.Idle = t.Idle
+		}
+	}
+}
+
+func New(l *slog.Logger, j *jobber.Jobber, opts ...Option) (*http.Server, error) {
+	t, err := parseTemplates(assets)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	s := &server{
+		logger:       l,
+		jobber:       j,
+		templates:    t,
+		dbTimeout:    defaultDBTimeout,
+		rateLimit:    defaultRateLimit,
+		feedCacheTTL: defaultFeedCacheTTL,
+		timeouts: Timeouts{
+			ReadHeader: defaultReadHeaderTimeout,
+			Read:       defaultReadTimeout,
+			Write:      defaultWriteTimeout,
+			Idle:       defaultIdleTimeout,
+		},
+	}
+	for _, o := range opts {
+		o(s)
 	}
-	s := &server{logger: l, jobber: j, templates: t}
+	if s.rateLimit > 0 {
+		s.limiter = newRateLimiter(s.rateLimit)
+	}
+	if s.feedCacheTTL > 0 {
+		s.feeds = newFeedCache(s.feedCacheTTL)
+	}
+	// The text responses are gzipped, the images are already compressed and
+	// promhttp compresses the metrics itself.
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /feeds", s.feed())
-	mux.HandleFunc("POST /feeds", s.create())
+	mux.HandleFunc("GET /feeds", gzipped(s.feed()))
+	mux.HandleFunc("GET /feeds/combined", gzipped(s.combined()))
+	mux.HandleFunc("POST /feeds/combined", s.limit(s.authorize(s.createCombined())))
+	mux.HandleFunc("GET /feeds/fragment", gzipped(s.fragment()))
+	mux.HandleFunc("PUT /feeds/notifications", s.limit(s.authorize(s.notifications())))
+	mux.HandleFunc("POST /feeds", s.limit(s.authorize(s.create())))
+	mux.HandleFunc("DELETE /feeds", s.limit(s.authorize(s.delete())))
+	if s.adminSecret != "" {
+		mux.HandleFunc("POST /feeds/refresh", s.admin(s.refresh()))
+		mux.HandleFunc("GET /admin/pause", s.admin(s.pauseStatus()))
+		mux.HandleFunc("POST /admin/pause", s.admin(s.pause()))
+		mux.HandleFunc("POST /admin/resume", s.admin(s.resume()))
+		mux.HandleFunc("GET /admin/scrape-debug", s.admin(s.scrapeDebug()))
+	}
+	mux.HandleFunc("GET /api/queries/{keywords}/{location}/count", s.count())
+	mux.HandleFunc("GET /search", gzipped(s.search()))
+	mux.HandleFunc("GET /stats/companies", gzipped(s.companies()))
+	if s.logos != nil {
+		mux.HandleFunc("GET /img", s.img())
+	}
 	mux.Handle("GET /metrics", promhttp.Handler())
-	mux.HandleFunc("GET /help", s.help())
-	mux.HandleFunc("/", s.index())
+	mux.HandleFunc("GET /healthz", s.healthz())
+	mux.HandleFunc("GET /readyz", s.readyz())
+	mux.HandleFunc("GET /help", gzipped(s.help()))
+	mux.HandleFunc("/", gzipped(s.index()))
 
 	return &http.Server{
 		Addr:              ":80",
 		Handler:           metrics.HTTPMiddleware(mux),
-		ReadHeaderTimeout: 10 * time.Second,
+		ReadHeaderTimeout: s.timeouts.ReadHeader,
+		ReadTimeout:       s.timeouts.Read,
+		WriteTimeout:      s.timeouts.Write,
+		IdleTimeout:       s.timeouts.Idle,
 	}, nil
 }
 
+// requiredAssets are the templates the handlers execute.
+var requiredAssets = []string{assetIndex, assetHelp, assetRSS, assetAtom, assetCreateResponse, assetOffersFragment}
+
+// parseTemplates parses the assets one by one, so errors name the failing
+// template, and checks all the templates the handlers execute are there.
+func parseTemplates(fsys fs.FS) (*template.Template, error) {
+	files, err := fs.Glob(fsys, assetsGlob)
+	if err != nil {
+		return nil, fmt.Errorf("invalid assets glob %s: %w", assetsGlob, err)
+	}
+	t := template.New("").Funcs(funcMap)
+	for _, f := range files {
+		b, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read template %s: %w", f, err)
+		}
+		if _, err := t.New(path.Base(f)).Parse(string(b)); err != nil {
+			return nil, fmt.Errorf("syntax error in template %s: %w", f, err)
+		}
+	}
+	for _, a := range requiredAssets {
+		if t.Lookup(a) == nil {
+			return nil, fmt.Errorf("missing template %s", a)
+		}
+	}
+	return t, nil
+}
+
+type indexData struct {
+	Popular []*db.Query
+}
+
 func (s *server) index() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" || r.Method != http.MethodGet {
 			http.NotFound(w, r)
 			return
 		}
-		if err := s.templates.ExecuteTemplate(w, assetIndex, nil); err != nil {
+		d := &indexData{}
+		if s.popularFeeds > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+			defer cancel()
+			// The index is still useful without them, so errors aren't fatal.
+			popular, err := s.jobber.ListPopularQueries(ctx, s.popularFeeds)
+			if err != nil {
+				s.logger.Error("failed to list popular queries in server.index", slog.String("error", err.Error()))
+			}
+			d.Popular = popular
+		}
+		if err := s.templates.ExecuteTemplate(w, assetIndex, d); err != nil {
 			s.internalError(w, "failed to execute template in server.index", err)
 			return
 		}
@@ -83,15 +357,50 @@ func (s *server) help() http.HandlerFunc {
 	}
 }
 
+// createResponse is the create endpoint's response for API clients.
+type createResponse struct {
+	FeedURL   string `json:"feed_url"`
+	Gathering bool   `json:"gathering"` // The initial scrape is still running.
+}
+
+type createData struct {
+	FeedURL   string
+	Gathering bool
+}
+
+// acceptsJSON reports whether the request's Accept header asks for JSON.
+func acceptsJSON(r *http.Request) bool {
+	for _, v := range strings.Split(r.Header.Get("Accept"), ",") {
+		if t, _, err := mime.ParseMediaType(v); err == nil && t == "application/json" {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *server) create() http.HandlerFunc {
+	group := s.createGroup()
 	return func(w http.ResponseWriter, r *http.Request) {
-		params, err := validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+		// Repeated keywords and locations create a query group instead.
+		if r.ParseForm() == nil && (len(r.Form[queryParamKeywords]) > 1 || len(r.Form[queryParamLocation]) > 1) {
+			group(w, r)
+			return
+		}
+		params, err := feedParams(w, r)
 		if err != nil {
 			s.logger.Info("missing params in server.create", slog.String("error", err.Error()))
 			return
 		}
-		if err := s.jobber.CreateQuery(params.Get(queryParamKeywords), params.Get(queryParamLocation)); err != nil {
-			s.internalError(w, "failed to create query", err)
+		opts, err := parseQueryOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		done, err := s.jobber.CreateQuery(ctx, params.Get(queryParamKeywords), params.Get(queryParamLocation), opts)
+		if err != nil && !errors.Is(err, jobber.ErrQueryExists) {
+			s.createError(w, "server.create", err)
 			return
 		}
 
@@ -101,50 +410,1119 @@ func (s *server) create() http.HandlerFunc {
 			return
 		}
 		u.RawQuery = params.Encode()
+		s.created(w, r, "server.create", u.String(), done)
+	}
+}
+
+// createGroup creates a query group out of the repeated keywords and location
+// params, paired in order, whose feed merges the offers of all its queries.
+func (s *server) createGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queries, err := parseGroupQueries(r)
+		if err != nil {
+			s.logger.Info("invalid params in server.createGroup", slog.String("error", err.Error()))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts, err := parseQueryOptions(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		id, done, err := s.jobber.CreateQueryGroup(ctx, queries, opts)
+		if err != nil {
+			s.createError(w, "server.createGroup", err)
+			return
+		}
 
-		if err := s.templates.ExecuteTemplate(w, assetCreateResponse, u.String()); err != nil {
-			s.internalError(w, "failed to execute template in server.create", err)
+		u, err := url.Parse("https://" + r.Host + "/feeds")
+		if err != nil {
+			s.internalError(w, "failed to parse url in server.createGroup", err)
 			return
 		}
+		u.RawQuery = url.Values{queryParamGroup: {strconv.FormatInt(id, 10)}}.Encode()
+		s.created(w, r, "server.createGroup", u.String(), done)
+	}
+}
+
+// parseQueryOptions parses the optional params of the created queries,
+// leaving the missing ones to jobber's defaults.
+func parseQueryOptions(r *http.Request) (jobber.QueryOptions, error) {
+	opts := jobber.QueryOptions{
+		Since:  strings.ToLower(strings.TrimSpace(r.FormValue(queryParamSince))),
+		Remote: r.FormValue(queryParamRemote) == "true",
+		GeoID:  strings.TrimSpace(r.FormValue(queryParamGeoID)),
+	}
+	if v := r.FormValue(queryParamInterval); v != "" {
+		interval, err := strconv.Atoi(v)
+		if err != nil {
+			return jobber.QueryOptions{}, fmt.Errorf("invalid %s: %s", queryParamInterval, v)
+		}
+		opts.IntervalHours = interval
 	}
+	return opts, nil
+}
+
+// parseGroupQueries pairs the repeated keywords and location params of a
+// query group, normalized like validateParams does.
+func parseGroupQueries(r *http.Request) ([]jobber.GroupQuery, error) {
+	keywords, locations := r.Form[queryParamKeywords], r.Form[queryParamLocation]
+	if len(keywords) != len(locations) {
+		return nil, fmt.Errorf("unpaired params: %d %s and %d %s", len(keywords), queryParamKeywords, len(locations), queryParamLocation)
+	}
+	if len(keywords) > maxCombinedQueries {
+		return nil, fmt.Errorf("too many queries: %d, max %d", len(keywords), maxCombinedQueries)
+	}
+	queries := make([]jobber.GroupQuery, len(keywords))
+	for i := range keywords {
+		queries[i] = jobber.GroupQuery{
+			Keywords: strings.ToLower(strings.TrimSpace(keywords[i])),
+			Location: strings.ToLower(strings.TrimSpace(locations[i])),
+		}
+		if queries[i].Keywords == "" || queries[i].Location == "" {
+			return nil, fmt.Errorf("missing params: query %d has no %s or %s", i+1, queryParamKeywords, queryParamLocation)
+		}
+	}
+	return queries, nil
+}
+
+// createError responds to a failed query creation in the handler.
+func (s *server) createError(w http.ResponseWriter, handler string, err error) {
+	if errors.Is(err, jobber.ErrInvalidQuery) || errors.Is(err, jobber.ErrInvalidInterval) || errors.Is(err, jobber.ErrInvalidTimePostedRange) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, jobber.ErrQueryConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.unavailable(w, "db timeout in "+handler, err)
+		return
+	}
+	s.internalError(w, "failed to create query in "+handler, err)
+}
+
+// created responds with the feed URL of the created query, as JSON or as the htmx fragment.
+func (s *server) created(w http.ResponseWriter, r *http.Request, handler, feedURL string, done bool) {
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(createResponse{FeedURL: feedURL, Gathering: !done}); err != nil {
+			s.logger.Error("failed to write response in "+handler, slog.String("error", err.Error()))
+		}
+		return
+	}
+	d := &createData{FeedURL: feedURL, Gathering: !done}
+	if err := s.templates.ExecuteTemplate(w, assetCreateResponse, d); err != nil {
+		s.internalError(w, "failed to execute template in "+handler, err)
+	}
+}
+
+func (s *server) delete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.delete", slog.String("error", err.Error()))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		if err := s.jobber.DeleteQuery(ctx, params.Get(queryParamKeywords), params.Get(queryParamLocation)); err != nil {
+			if errors.Is(err, jobber.ErrQueryNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			s.internalError(w, "failed to delete query in server.delete", err)
+			return
+		}
+		if s.feeds != nil {
+			// The deleted query's cached feeds aren't tracked by query.
+			s.feeds.clear()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// refresh runs a query right away, without waiting for its next scheduled run.
+func (s *server) refresh() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.refresh", slog.String("error", err.Error()))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		if err := s.jobber.RunNow(ctx, params.Get(queryParamKeywords), params.Get(queryParamLocation)); err != nil {
+			if errors.Is(err, jobber.ErrQueryNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			s.internalError(w, "failed to run query in server.refresh", err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// pauseResponse tells whether the scheduling is paused.
+type pauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// pauseStatus responds whether the scheduling is paused.
+func (s *server) pauseStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s.writePauseResponse(w)
+	}
+}
+
+// pause stops scraping, ie. while LinkedIn blocks us, keeping the queries.
+func (s *server) pause() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if err := s.jobber.Pause(); err != nil {
+			s.internalError(w, "failed to pause in server.pause", err)
+			return
+		}
+		s.writePauseResponse(w)
+	}
+}
+
+// resume starts scraping again after a pause.
+func (s *server) resume() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s.jobber.Resume()
+		s.writePauseResponse(w)
+	}
+}
+
+func (s *server) writePauseResponse(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(pauseResponse{Paused: s.jobber.Paused()}); err != nil {
+		s.logger.Error("failed to write response in server.writePauseResponse", slog.String("error", err.Error()))
+	}
+}
+
+// scrapeDebugResponse has the scraper's debug info, and its error if it failed.
+type scrapeDebugResponse struct {
+	Debug *scrape.DebugInfo `json:"debug"`
+	Error string            `json:"error,omitempty"`
+}
+
+// scrapeDebug scrapes a query without storing it, responding with
+// the scraper's selectors match counts to debug its parsing.
+func (s *server) scrapeDebug() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.scrapeDebug", slog.String("error", err.Error()))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), scrapeDebugTimeout)
+		defer cancel()
+		remote := r.FormValue(queryParamRemote) == "true"
+		info, err := s.jobber.ScrapeDebug(ctx, params.Get(queryParamKeywords), params.Get(queryParamLocation), remote)
+		if info == nil {
+			switch {
+			case errors.Is(err, jobber.ErrInvalidQuery):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, jobber.ErrDebugUnsupported):
+				http.Error(w, err.Error(), http.StatusNotImplemented)
+			default:
+				s.internalError(w, "failed to scrape in server.scrapeDebug", err)
+			}
+			return
+		}
+		resp := scrapeDebugResponse{Debug: info}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.logger.Error("failed to write response in server.scrapeDebug", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// admin responds with 401 to the requests without the admin secret.
+func (s *server) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !secretMatches(r.Header.Get(headerAdminSecret), s.adminSecret) {
+			s.logger.Info("unauthorized admin request", slog.String("path", r.URL.Path))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authorize responds with 401 to the requests without the API key, if there's one.
+func (s *server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	if s.apiKey == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(headerAPIKey)
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			key = bearer
+		}
+		if !secretMatches(key, s.apiKey) {
+			s.logger.Info("unauthorized request", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// secretMatches compares the secrets in constant time, not to leak how much of it was guessed.
+func secretMatches(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// notifications opts a query into webhook notifications of its new offers.
+func (s *server) notifications() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamKeywords, queryParamLocation, queryParamWebhook}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.notifications", slog.String("error", err.Error()))
+			return
+		}
+		p := jobber.NotificationPrefs{
+			// The webhook url isn't lowercased like the other params.
+			WebhookURL:       strings.TrimSpace(r.FormValue(queryParamWebhook)),
+			RemoteOnly:       r.FormValue(queryParamRemoteOnly) == "true",
+			NewCompaniesOnly: r.FormValue(queryParamNewCompaniesOnly) == "true",
+		}
+		if v := r.FormValue(queryParamMinSalary); v != "" {
+			if p.MinSalary, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", queryParamMinSalary, v), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		if err := s.jobber.SetNotification(ctx, params.Get(queryParamKeywords), params.Get(queryParamLocation), p); err != nil {
+			switch {
+			case errors.Is(err, jobber.ErrInvalidNotification):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, jobber.ErrQueryNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				s.unavailable(w, "db timeout in server.notifications", err)
+			default:
+				s.internalError(w, "failed to set notification in server.notifications", err)
+			}
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+type feedFormat struct {
+	asset       string // Empty for formats that aren't rendered with a template.
+	contentType string
+}
+
+var feedFormats = map[string]feedFormat{
+	formatRSS:  {asset: assetRSS, contentType: "application/rss+xml"},
+	formatAtom: {asset: assetAtom, contentType: "application/atom+xml"},
+	formatJSON: {contentType: "application/feed+json"},
 }
 
 type feedData struct {
-	Keywords string
-	Location string
-	Host     string
-	Offers   []*db.Offer
-	NotFound bool
+	Title     string
+	Keywords  string
+	Location  string
+	Host      string
+	Offers    []*db.Offer
+	NotFound  bool
+	Gathering bool // The query's initial scrape is still running.
+	LogoProxy bool
+	Updated   time.Time // Most recent offer creation time, used by Atom's <updated>.
+	TTL       int       // Minutes readers should cache the feed, used by RSS's <ttl>. Omitted when 0.
+	ImageURL  string
+	SourceURL string    // The scraped search URL, only set when enabled.
+	FailedAt  time.Time // When the query's last run failed, zero if it succeeded.
 }
 
 func (s *server) feed() http.HandlerFunc {
+	group := s.groupFeed()
 	return func(w http.ResponseWriter, r *http.Request) {
-		params, err := validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+		if r.FormValue(queryParamGroup) != "" {
+			group(w, r)
+			return
+		}
+		params, err := feedParams(w, r)
 		if err != nil {
 			s.logger.Info("missing params in server.feed", slog.String("error", err.Error()))
 			return
 		}
+		cacheKey := feedCacheKey(r)
+		if s.feeds != nil {
+			if f, touch, ok := s.feeds.get(cacheKey); ok {
+				if touch {
+					go s.touchQuery(params.Get(queryParamKeywords), params.Get(queryParamLocation))
+				}
+				metrics.FeedReads.WithLabelValues(params.Get(queryParamKeywords), params.Get(queryParamLocation)).Inc()
+				writeFeed(w, r, f)
+				return
+			}
+		}
+		format := formatRSS
+		if f := r.FormValue(queryParamFormat); f != "" {
+			format = strings.ToLower(f)
+		}
+		ff, ok := feedFormats[format]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported format: %s", format), http.StatusBadRequest)
+			return
+		}
+		page, err := parseFeedPage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		title := fmt.Sprintf("%s jobs in %s", params.Get(queryParamKeywords), params.Get(queryParamLocation))
+		if params.Get(queryParamLocation) == "" {
+			title = fmt.Sprintf("%s remote jobs", params.Get(queryParamKeywords))
+		}
 		d := &feedData{
-			Keywords: params.Get(queryParamKeywords),
-			Location: params.Get(queryParamLocation),
-			Host:     r.Host,
+			Title:     title,
+			Keywords:  params.Get(queryParamKeywords),
+			Location:  params.Get(queryParamLocation),
+			Host:      r.Host,
+			LogoProxy: s.logos != nil,
+			ImageURL:  s.imageURL,
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		var offers []*db.Offer
+		q, err := s.jobber.GetQuery(ctx, d.Keywords, d.Location)
+		// Queries are identified by their keywords and location, so a remote
+		// feed isn't served with the offers of a non remote query.
+		if err == nil && params.Get(queryParamRemote) == "true" && !q.Remote {
+			err = fmt.Errorf("%w: the query isn't remote", jobber.ErrQueryNotFound)
+		}
+		if err == nil {
+			offers, err = s.jobber.ListOffers(ctx, d.Keywords, d.Location, page.limit, page.offset)
 		}
-		offers, err := s.jobber.ListOffers(params.Get(queryParamKeywords), params.Get(queryParamLocation))
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
+			switch {
+			case errors.Is(err, jobber.ErrQueryNotFound):
 				d.NotFound = true
 				s.logger.Info("no query found in server.feed", slog.Any("params", params), slog.String("error", err.Error()))
-			} else {
+			case errors.Is(err, context.DeadlineExceeded):
+				s.unavailable(w, "db timeout in server.feed", err)
+				return
+			default:
 				s.internalError(w, "failed to get query in server.feed", err)
 				return
 			}
 		}
+		offers = filterCompanies(offers, r.Form[queryParamCompany], r.Form[queryParamExcludeCompany])
+		offers = filterMaxAge(offers, page.maxAge, time.Now())
 		d.Offers = offers
-		w.Header().Add("Content-Type", "application/rss+xml")
-		if err := s.templates.ExecuteTemplate(w, assetRSS, d); err != nil {
+		d.Gathering = !d.NotFound && s.jobber.Gathering(d.Keywords, d.Location)
+		if !d.NotFound {
+			metrics.FeedReads.WithLabelValues(d.Keywords, d.Location).Inc()
+			d.TTL = ttl(q.IntervalHours)
+			if q.LastError != "" {
+				d.FailedAt = q.LastErrorAt.Time
+			}
+			if s.sourceURL != nil {
+				d.SourceURL = s.sourceURL(q)
+			}
+		}
+		d.Updated = time.Now()
+		var lastModified time.Time
+		if len(offers) > 0 {
+			d.Updated = offers[0].CreatedAt.Time
+			for _, o := range offers {
+				if o.CreatedAt.Time.After(d.Updated) {
+					d.Updated = o.CreatedAt.Time
+				}
+			}
+			lastModified = d.Updated
+		}
+		// The feed is rendered before writing it, so it can be cached.
+		var buf bytes.Buffer
+		if format == formatJSON {
+			if err := writeJSONFeed(&buf, d); err != nil {
+				s.internalError(w, "failed to write json feed in server.feed", err)
+				return
+			}
+		} else if err := s.templates.ExecuteTemplate(&buf, ff.asset, d); err != nil {
 			s.internalError(w, "failed to execute template in server.feed", err)
 			return
 		}
+		f := cachedFeed{
+			contentType:  ff.contentType,
+			body:         buf.Bytes(),
+			etag:         feedETag(buf.Bytes()),
+			lastModified: lastModified,
+		}
+		if s.feeds != nil && !d.NotFound && !d.Gathering {
+			s.feeds.store(cacheKey, f)
+		}
+		if d.Gathering {
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(gatheringMaxAge.Seconds())))
+		}
+		writeFeed(w, r, f)
+	}
+}
+
+// groupFeed serves the RSS feed of a query group, merging the offers of its
+// queries. Their pagination and filters apply to the merged offers, whose
+// pages end within the first jobber.MaxOffersLimit ones.
+func (s *server) groupFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.FormValue(queryParamGroup), 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, fmt.Sprintf("invalid %s: %s", queryParamGroup, r.FormValue(queryParamGroup)), http.StatusBadRequest)
+			return
+		}
+		// Like combined feeds, groups have no single keywords and location
+		// for the Atom and JSON feeds' IDs and URLs.
+		if f := strings.ToLower(r.FormValue(queryParamFormat)); f != "" && f != formatRSS {
+			http.Error(w, fmt.Sprintf("unsupported format: %s", f), http.StatusBadRequest)
+			return
+		}
+		page, err := parseFeedPage(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit := page.limit
+		if limit == 0 {
+			limit = jobber.DefaultOffersLimit
+		}
+		limit = min(limit, jobber.MaxOffersLimit)
+		// Each query lists its offers up to the end of the page, which can't
+		// go past the offers a query lists at most.
+		if page.offset+limit > jobber.MaxOffersLimit {
+			http.Error(w, fmt.Sprintf("%s plus %s can't be over %d in group feeds", queryParamOffset, queryParamLimit, jobber.MaxOffersLimit), http.StatusBadRequest)
+			return
+		}
+		cacheKey := feedCacheKey(r)
+		if s.feeds != nil {
+			if f, _, ok := s.feeds.get(cacheKey); ok {
+				// The queries are touched when the feed is rendered again.
+				writeFeed(w, r, f)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		queries, err := s.jobber.ListQueryGroup(ctx, id)
+		if err != nil {
+			switch {
+			case errors.Is(err, jobber.ErrQueryGroupNotFound):
+				s.logger.Info("no query group found in server.groupFeed", slog.Int64("groupID", id))
+				http.NotFound(w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				s.unavailable(w, "db timeout in server.groupFeed", err)
+			default:
+				s.internalError(w, "failed to list query group in server.groupFeed", err)
+			}
+			return
+		}
+
+		d := &feedData{
+			Host:      r.Host,
+			LogoProxy: s.logos != nil,
+			ImageURL:  s.imageURL,
+		}
+		var (
+			titles []string
+			lists  [][]*db.Offer
+		)
+		for _, q := range queries {
+			titles = append(titles, fmt.Sprintf("%s jobs in %s", q.Keywords, q.Location))
+			// Each query lists enough offers to fill the page of the merged ones.
+			listCtx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+			offers, err := s.jobber.ListOffers(listCtx, q.Keywords, q.Location, page.offset+limit, 0)
+			cancel()
+			if err != nil {
+				switch {
+				case errors.Is(err, jobber.ErrQueryNotFound):
+					// Deleted since listing the group, which is deleted with it.
+					continue
+				case errors.Is(err, context.DeadlineExceeded):
+					s.unavailable(w, "db timeout in server.groupFeed", err)
+				default:
+					s.internalError(w, "failed to list offers in server.groupFeed", err)
+				}
+				return
+			}
+			lists = append(lists, offers)
+			metrics.FeedReads.WithLabelValues(q.Keywords, q.Location).Inc()
+			d.Gathering = d.Gathering || s.jobber.Gathering(q.Keywords, q.Location)
+			// The feed changes as often as its most often scraped query.
+			if t := ttl(q.IntervalHours); d.TTL == 0 || t < d.TTL {
+				d.TTL = t
+			}
+			if q.LastError != "" && q.LastErrorAt.Time.After(d.FailedAt) {
+				d.FailedAt = q.LastErrorAt.Time
+			}
+		}
+		d.Title = strings.Join(titles, ", ")
+		offers := jobber.MergeOffers(lists...)
+		offers = offers[min(page.offset, len(offers)):]
+		offers = offers[:min(limit, len(offers))]
+		offers = filterCompanies(offers, r.Form[queryParamCompany], r.Form[queryParamExcludeCompany])
+		d.Offers = filterMaxAge(offers, page.maxAge, time.Now())
+
+		d.Updated = time.Now()
+		var lastModified time.Time
+		for _, o := range d.Offers {
+			if o.CreatedAt.Time.After(lastModified) {
+				lastModified = o.CreatedAt.Time
+			}
+		}
+		if !lastModified.IsZero() {
+			d.Updated = lastModified
+		}
+		var buf bytes.Buffer
+		if err := s.templates.ExecuteTemplate(&buf, assetRSS, d); err != nil {
+			s.internalError(w, "failed to execute template in server.groupFeed", err)
+			return
+		}
+		f := cachedFeed{
+			contentType:  feedFormats[formatRSS].contentType,
+			body:         buf.Bytes(),
+			etag:         feedETag(buf.Bytes()),
+			lastModified: lastModified,
+		}
+		if s.feeds != nil && !d.Gathering {
+			s.feeds.store(cacheKey, f)
+		}
+		if d.Gathering {
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(gatheringMaxAge.Seconds())))
+		}
+		writeFeed(w, r, f)
+	}
+}
+
+// feedPage bounds the offers of a feed.
+type feedPage struct {
+	limit  int
+	offset int
+	maxAge time.Duration
+}
+
+// parseFeedPage parses the optional limit, offset and max age params of a feed.
+func parseFeedPage(r *http.Request) (feedPage, error) {
+	var (
+		p   feedPage
+		err error
+	)
+	if v := r.FormValue(queryParamLimit); v != "" {
+		if p.limit, err = strconv.Atoi(v); err != nil || p.limit < 0 {
+			return feedPage{}, fmt.Errorf("invalid %s: %s", queryParamLimit, v)
+		}
+	}
+	if v := r.FormValue(queryParamOffset); v != "" {
+		if p.offset, err = strconv.Atoi(v); err != nil || p.offset < 0 {
+			return feedPage{}, fmt.Errorf("invalid %s: %s", queryParamOffset, v)
+		}
+	}
+	if v := r.FormValue(queryParamMaxAge); v != "" {
+		if p.maxAge, err = parseMaxAge(v); err != nil {
+			return feedPage{}, fmt.Errorf("invalid %s: %s", queryParamMaxAge, v)
+		}
+	}
+	return p, nil
+}
+
+// feedETag is a strong validator of a rendered feed, so readers polling
+// it with If-None-Match get a 304 Not Modified until it changes.
+func feedETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeFeed writes a rendered feed with its validators. http.ServeContent
+// answers the conditional requests, If-None-Match taking precedence over
+// If-Modified-Since, which is only checked for feeds with offers.
+func writeFeed(w http.ResponseWriter, r *http.Request, f cachedFeed) {
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("ETag", f.etag)
+	http.ServeContent(w, r, "", f.lastModified, bytes.NewReader(f.body))
+}
+
+// touchQuery marks the query of a feed served from the cache as read,
+// so it isn't deleted for being unused.
+func (s *server) touchQuery(keywords, location string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.dbTimeout)
+	defer cancel()
+	if err := s.jobber.TouchQuery(ctx, keywords, location); err != nil {
+		s.logger.Error("failed to touch query in server.touchQuery", slog.String("keywords", keywords), slog.String("location", location), slog.String("error", err.Error()))
+	}
+}
+
+// filterCompanies keeps the offers from the included companies, or all of them
+// when none is, minus the ones from the excluded companies. Companies are
+// matched case-insensitively.
+func filterCompanies(offers []*db.Offer, include, exclude []string) []*db.Offer {
+	if len(include) == 0 && len(exclude) == 0 {
+		return offers
+	}
+	matches := func(companies []string, o *db.Offer) bool {
+		return slices.ContainsFunc(companies, func(c string) bool {
+			return strings.EqualFold(strings.TrimSpace(c), o.Company)
+		})
+	}
+	return slices.DeleteFunc(offers, func(o *db.Offer) bool {
+		return (len(include) > 0 && !matches(include, o)) || matches(exclude, o)
+	})
+}
+
+// parseMaxAge parses a Go duration, or a number of days like "3d".
+func parseMaxAge(v string) (time.Duration, error) {
+	var (
+		d   time.Duration
+		err error
+	)
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(v)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("max age must be positive: %s", v)
+	}
+	return d, nil
+}
+
+// filterMaxAge drops the offers posted longer than maxAge ago, or created
+// when their posting time is unknown. A zero maxAge keeps all of them.
+func filterMaxAge(offers []*db.Offer, maxAge time.Duration, now time.Time) []*db.Offer {
+	if maxAge == 0 {
+		return offers
+	}
+	return slices.DeleteFunc(offers, func(o *db.Offer) bool {
+		t := o.PostedAt
+		if !t.Valid {
+			t = o.CreatedAt
+		}
+		return now.Sub(t.Time) > maxAge
+	})
+}
+
+// searchResponse is the search endpoint's response for API clients.
+type searchResponse struct {
+	Query  string        `json:"query"`
+	Offers []searchOffer `json:"offers"`
+}
+
+type searchOffer struct {
+	Source   string    `json:"source"`
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Company  string    `json:"company"`
+	Location string    `json:"location"`
+	Salary   string    `json:"salary,omitempty"`
+	URL      string    `json:"url"`
+	PostedAt time.Time `json:"posted_at"`
+}
+
+// search serves the stored offers of all the queries matching the q param,
+// as RSS or as JSON when the request accepts it.
+func (s *server) search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamQuery}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.search", slog.String("error", err.Error()))
+			return
+		}
+		var limit int
+		if v := r.FormValue(queryParamLimit); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+				http.Error(w, fmt.Sprintf("invalid %s: %s", queryParamLimit, v), http.StatusBadRequest)
+				return
+			}
+		}
+		search := params.Get(queryParamQuery)
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		offers, err := s.jobber.SearchOffers(ctx, search, limit)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				s.unavailable(w, "db timeout in server.search", err)
+				return
+			}
+			s.internalError(w, "failed to search offers in server.search", err)
+			return
+		}
+
+		if acceptsJSON(r) {
+			resp := searchResponse{Query: search, Offers: []searchOffer{}}
+			for _, o := range offers {
+				resp.Offers = append(resp.Offers, searchOffer{
+					Source:   o.Source,
+					ID:       o.ID,
+					Title:    o.Title,
+					Company:  o.Company,
+					Location: o.Location,
+					Salary:   o.Salary,
+					URL:      offerLink(o),
+					PostedAt: o.PostedAt.Time,
+				})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(resp); err != nil {
+				s.logger.Error("failed to write response in server.search", slog.String("error", err.Error()))
+			}
+			return
+		}
+		d := &feedData{
+			Title:     fmt.Sprintf("%s jobs", search),
+			Host:      r.Host,
+			Offers:    offers,
+			LogoProxy: s.logos != nil,
+			ImageURL:  s.imageURL,
+		}
+		w.Header().Add("Content-Type", feedFormats[formatRSS].contentType)
+		if err := s.templates.ExecuteTemplate(w, assetRSS, d); err != nil {
+			s.internalError(w, "failed to execute template in server.search", err)
+			return
+		}
+	}
+}
+
+type countResponse struct {
+	Keywords string `json:"keywords"`
+	Location string `json:"location"`
+	Count    int64  `json:"count"`
+}
+
+// shieldsResponse is the schema of shields.io's endpoint badges.
+type shieldsResponse struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// count serves the amount of offers of a query, for badges and dashboards.
+func (s *server) count() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keywords := strings.ToLower(strings.TrimSpace(r.PathValue(queryParamKeywords)))
+		location := strings.ToLower(strings.TrimSpace(r.PathValue(queryParamLocation)))
+		format := formatJSON
+		if f := r.FormValue(queryParamFormat); f != "" {
+			format = strings.ToLower(f)
+		}
+		if format != formatJSON && format != formatText && format != formatShields {
+			http.Error(w, fmt.Sprintf("unsupported format: %s", format), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		n, err := s.jobber.CountOffers(ctx, keywords, location)
+		if err != nil {
+			switch {
+			case errors.Is(err, jobber.ErrQueryNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				s.unavailable(w, "db timeout in server.count", err)
+			default:
+				s.internalError(w, "failed to count offers in server.count", err)
+			}
+			return
+		}
+
+		var resp any = countResponse{Keywords: keywords, Location: location, Count: n}
+		switch format {
+		case formatText:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if _, err := fmt.Fprint(w, n); err != nil {
+				s.logger.Error("failed to write response in server.count", slog.String("error", err.Error()))
+			}
+			return
+		case formatShields:
+			resp = shieldsResponse{
+				SchemaVersion: 1,
+				Label:         fmt.Sprintf("%s jobs in %s", keywords, location),
+				Message:       strconv.FormatInt(n, 10),
+				Color:         "blue",
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.logger.Error("failed to write response in server.count", slog.String("error", err.Error()))
+		}
+	}
+}
+
+type companyCount struct {
+	Company string `json:"company"`
+	Count   int64  `json:"count"`
+}
+
+// companies serves how many offers of a query each company has, to see who's hiring the most.
+func (s *server) companies() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.companies", slog.String("error", err.Error()))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		rows, err := s.jobber.CountOffersByCompany(ctx, params.Get(queryParamKeywords), params.Get(queryParamLocation))
+		if err != nil {
+			switch {
+			case errors.Is(err, jobber.ErrQueryNotFound):
+				http.NotFound(w, r)
+			case errors.Is(err, context.DeadlineExceeded):
+				s.unavailable(w, "db timeout in server.companies", err)
+			default:
+				s.internalError(w, "failed to count offers by company in server.companies", err)
+			}
+			return
+		}
+
+		resp := make([]companyCount, 0, len(rows))
+		for _, c := range rows {
+			resp = append(resp, companyCount{Company: c.Company, Count: c.OfferCount})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			s.logger.Error("failed to write response in server.companies", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// fragment serves the query's offers list as an HTML fragment,
+// so pages can poll it with htmx and swap it in place.
+func (s *server) fragment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.fragment", slog.String("error", err.Error()))
+			return
+		}
+		d := &feedData{
+			Keywords: params.Get(queryParamKeywords),
+			Location: params.Get(queryParamLocation),
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		offers, err := s.jobber.ListOffers(ctx, d.Keywords, d.Location, 0, 0)
+		if err != nil {
+			switch {
+			case errors.Is(err, jobber.ErrQueryNotFound):
+				// We still respond with 200, htmx doesn't swap error responses.
+				d.NotFound = true
+			case errors.Is(err, context.DeadlineExceeded):
+				s.unavailable(w, "db timeout in server.fragment", err)
+				return
+			default:
+				s.internalError(w, "failed to list offers in server.fragment", err)
+				return
+			}
+		}
+		d.Offers = offers
+		d.Gathering = !d.NotFound && s.jobber.Gathering(d.Keywords, d.Location)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := s.templates.ExecuteTemplate(w, assetOffersFragment, d); err != nil {
+			s.internalError(w, "failed to execute template in server.fragment", err)
+			return
+		}
+	}
+}
+
+// combined serves a single RSS feed merging the offers of several
+// queries, passed as repeated keywords|location pairs in the q param.
+func (s *server) combined() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queries, err := parseCombinedQueries(r)
+		if err != nil {
+			s.logger.Info("invalid params in server.combined", slog.String("error", err.Error()))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var (
+			titles []string
+			lists  [][]*db.Offer
+			minTTL int
+		)
+		for _, q := range queries {
+			titles = append(titles, fmt.Sprintf("%s jobs in %s", q.keywords, q.location))
+			o, t, err := s.combinedOffers(r.Context(), q.keywords, q.location)
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) {
+					s.unavailable(w, "db timeout in server.combined", err)
+					return
+				}
+				s.internalError(w, "failed to list offers in server.combined", err)
+				return
+			}
+			lists = append(lists, o)
+			if t != 0 && (minTTL == 0 || t < minTTL) {
+				minTTL = t
+			}
+		}
+
+		d := &feedData{
+			Title:     strings.Join(titles, ", "),
+			Host:      r.Host,
+			Offers:    jobber.MergeOffers(lists...),
+			LogoProxy: s.logos != nil,
+			TTL:       minTTL,
+			ImageURL:  s.imageURL,
+		}
+		w.Header().Add("Content-Type", feedFormats[formatRSS].contentType)
+		if err := s.templates.ExecuteTemplate(w, assetRSS, d); err != nil {
+			s.internalError(w, "failed to execute template in server.combined", err)
+			return
+		}
+	}
+}
+
+// createCombined creates the missing queries of a combined feed, passed like
+// for GET /feeds/combined, and responds with its URL like POST /feeds. The
+// queries are created concurrently, the feed is still gathering if any of
+// their initial scrapes timed out.
+func (s *server) createCombined() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queries, err := parseCombinedQueries(r)
+		if err != nil {
+			s.logger.Info("invalid params in server.createCombined", slog.String("error", err.Error()))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+		defer cancel()
+		var (
+			wg    sync.WaitGroup
+			dones = make([]bool, len(queries))
+			errs  = make([]error, len(queries))
+		)
+		for i, q := range queries {
+			wg.Go(func() {
+				dones[i], errs[i] = s.jobber.CreateQuery(ctx, q.keywords, q.location, jobber.QueryOptions{})
+			})
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, jobber.ErrQueryExists) {
+				s.createError(w, "server.createCombined", err)
+				return
+			}
+		}
+
+		v := url.Values{}
+		for _, q := range queries {
+			v.Add(queryParamQuery, q.keywords+"|"+q.location)
+		}
+		u, err := url.Parse("https://" + r.Host + "/feeds/combined")
+		if err != nil {
+			s.internalError(w, "failed to parse url in server.createCombined", err)
+			return
+		}
+		u.RawQuery = v.Encode()
+		s.created(w, r, "server.createCombined", u.String(), !slices.Contains(dones, false))
+	}
+}
+
+// combinedOffers lists the offers of one of the combined feed's queries,
+// along with the feed's TTL for it. Missing queries are skipped, they're
+// created with POST /feeds/combined.
+func (s *server) combinedOffers(ctx context.Context, keywords, location string) ([]*db.Offer, int, error) {
+	dbCtx, cancel := context.WithTimeout(ctx, s.dbTimeout)
+	defer cancel()
+	q, err := s.jobber.GetQuery(dbCtx, keywords, location)
+	if err == nil {
+		var offers []*db.Offer
+		if offers, err = s.jobber.ListOffers(dbCtx, keywords, location, 0, 0); err == nil {
+			return offers, ttl(q.IntervalHours), nil
+		}
+	}
+	if errors.Is(err, jobber.ErrQueryNotFound) {
+		s.logger.Info("no query found in server.combined", slog.String("keywords", keywords), slog.String("location", location))
+		return nil, 0, nil
+	}
+	return nil, 0, err
+}
+
+type combinedQuery struct {
+	keywords string
+	location string
+}
+
+// parseCombinedQueries parses and normalizes the keywords|location
+// pairs of a combined feed, ignoring repeated ones.
+func parseCombinedQueries(r *http.Request) ([]combinedQuery, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, fmt.Errorf("unable to parse params: %w", err)
+	}
+	values := r.Form[queryParamQuery]
+	if len(values) == 0 {
+		return nil, fmt.Errorf("missing params: [%s]", queryParamQuery)
+	}
+	if len(values) > maxCombinedQueries {
+		return nil, fmt.Errorf("too many queries: %d, max %d", len(values), maxCombinedQueries)
+	}
+	var queries []combinedQuery
+	for _, v := range values {
+		k, l, ok := strings.Cut(v, "|")
+		q := combinedQuery{
+			keywords: strings.ToLower(strings.TrimSpace(k)),
+			location: strings.ToLower(strings.TrimSpace(l)),
+		}
+		if !ok || q.keywords == "" || q.location == "" {
+			return nil, fmt.Errorf("invalid query %q, expected keywords|location", v)
+		}
+		if !slices.Contains(queries, q) {
+			queries = append(queries, q)
+		}
+	}
+	return queries, nil
+}
+
+func (s *server) img() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := validateParams([]string{queryParamOffer}, w, r)
+		if err != nil {
+			s.logger.Info("missing params in server.img", slog.String("error", err.Error()))
+			return
+		}
+		offer := offerKey{source: scrape.SourceLinkedIn, id: params.Get(queryParamOffer)}
+		if src := r.FormValue(queryParamSource); src != "" {
+			offer.source = strings.ToLower(strings.TrimSpace(src))
+		}
+		l, err := s.logos.get(r.Context(), offer)
+		if err != nil {
+			if errors.Is(err, jobber.ErrOfferNotFound) || errors.Is(err, errLogoNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			s.logger.Error("failed to get logo in server.img", slog.Any("params", params), slog.String("error", err.Error()))
+			http.Error(w, "unable to fetch logo", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", l.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(l.body)))
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(logoMaxAge.Seconds())))
+		if _, err := w.Write(l.body); err != nil {
+			s.logger.Error("failed to write logo in server.img", slog.String("error", err.Error()))
+		}
 	}
 }
 
@@ -153,6 +1531,35 @@ func (s *server) internalError(w http.ResponseWriter, msg string, err error) {
 	http.Error(w, "it's not you it's me", http.StatusInternalServerError)
 }
 
+// healthz reports the process is up.
+func (s *server) healthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok") //nolint: errcheck
+	}
+}
+
+// readyz reports whether the server can serve requests, ie. the DB is reachable.
+func (s *server) readyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.ping != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), s.dbTimeout)
+			defer cancel()
+			if err := s.ping(ctx); err != nil {
+				s.unavailable(w, "failed to ping db in server.readyz", err)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok") //nolint: errcheck
+	}
+}
+
+func (s *server) unavailable(w http.ResponseWriter, msg string, err error) {
+	s.logger.Warn(msg, slog.String("error", err.Error()))
+	http.Error(w, "try again later", http.StatusServiceUnavailable)
+}
+
 // validateParams receives a list of params, validate they've
 // been supplied in the request and normalizes them.
 // If a param is missing, it will respond with 400.
@@ -160,12 +1567,13 @@ func validateParams(params []string, w http.ResponseWriter, r *http.Request) (ur
 	missing := []string{}
 	valid := url.Values{}
 	for _, p := range params {
-		v := r.FormValue(p)
+		// Whitespace only values are as missing as empty ones.
+		v := strings.ToLower(strings.TrimSpace(r.FormValue(p)))
 		if v == "" {
 			missing = append(missing, p)
 			continue
 		}
-		valid.Add(p, strings.ToLower(strings.TrimSpace(v)))
+		valid.Add(p, v)
 	}
 	if len(missing) != 0 {
 		w.WriteHeader(http.StatusBadRequest)
@@ -178,6 +1586,38 @@ func validateParams(params []string, w http.ResponseWriter, r *http.Request) (ur
 	return valid, nil
 }
 
+// ttl returns the minutes readers should cache the feed of a query scraped every intervalHours.
+func ttl(intervalHours int32) int {
+	return int(intervalHours) * 60
+}
+
+// feedParams validates the keywords and location of a feed. Remote feeds can
+// leave the location empty to find offers anywhere, their params keep the
+// remote flag so the feed URL tells them apart.
+func feedParams(w http.ResponseWriter, r *http.Request) (url.Values, error) {
+	if r.FormValue(queryParamRemote) != "true" {
+		return validateParams([]string{queryParamKeywords, queryParamLocation}, w, r)
+	}
+	params, err := validateParams([]string{queryParamKeywords}, w, r)
+	if err != nil {
+		return nil, err
+	}
+	if l := strings.ToLower(strings.TrimSpace(r.FormValue(queryParamLocation))); l != "" {
+		params.Set(queryParamLocation, l)
+	}
+	params.Set(queryParamRemote, "true")
+	return params, nil
+}
+
+// offerLink returns the offer's job posting URL. Offers
+// scraped before we stored it fall back to LinkedIn's.
+func offerLink(o *db.Offer) string {
+	if o.Url != "" {
+		return o.Url
+	}
+	return "https://www.linkedin.com/jobs/view/" + o.ID
+}
+
 var funcMap = template.FuncMap{
 	"createdAt": func(o *db.Offer) string {
 		return o.CreatedAt.Time.Format(time.RFC1123Z)
@@ -186,7 +1626,18 @@ var funcMap = template.FuncMap{
 		t := fmt.Sprintf("%s at %s (posted %s)", o.Title, o.Company, o.PostedAt.Time.Format("Jan 2"))
 		return html.EscapeString(t)
 	},
+	"link": offerLink,
+	// cdata escapes the end of CDATA sections in HTML embedded in one.
+	"cdata": func(s string) string {
+		return strings.ReplaceAll(s, "]]>", "]]&gt;")
+	},
 	"now": func() string {
 		return time.Now().Format(time.RFC1123Z)
 	},
+	"rfc3339": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+	"rfc1123": func(t time.Time) string {
+		return t.Format(time.RFC1123Z)
+	},
 }
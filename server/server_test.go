@@ -1,18 +1,36 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
 	"io"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/alwedo/jobber/db"
 	"github.com/alwedo/jobber/jobber"
+	"github.com/alwedo/jobber/metrics"
 	"github.com/alwedo/jobber/scrape"
 	approvals "github.com/approvals/go-approval-tests"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestServer(t *testing.T) {
@@ -45,6 +63,122 @@ func TestServer(t *testing.T) {
 			},
 			wantStatus: http.StatusOK,
 		},
+		{
+			name:   "with 6 hours interval",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+				queryParamLocation: "berlin",
+				queryParamInterval: "6",
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "with a month time posted range",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+				queryParamLocation: "hamburg",
+				queryParamSince:    "month",
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "with remote offers only",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+				queryParamLocation: "porto",
+				queryParamRemote:   "true",
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "remote offers without a location",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+				queryParamRemote:   "true",
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "remote feed without a location",
+			path:   "/feeds",
+			method: http.MethodGet,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+				queryParamRemote:   "true",
+			},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Content-Type": "application/rss+xml"},
+		},
+		{
+			name:   "remote offers of a non remote query",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "golang",
+				queryParamLocation: "berlin",
+				queryParamRemote:   "true",
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:   "without a location nor remote offers",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "with invalid time posted range",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+				queryParamLocation: "munich",
+				queryParamSince:    "decade",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "with whitespace only keywords",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "   ",
+				queryParamLocation: "berlin",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "with keywords empty once canonicalized",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: " , ",
+				queryParamLocation: "berlin",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "with interval not dividing a day",
+			path:   "/feeds",
+			method: http.MethodPost,
+			params: map[string]string{
+				queryParamKeywords: "rust",
+				queryParamLocation: "munich",
+				queryParamInterval: "5",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:   "with missing param keywords",
 			path:   "/feeds",
@@ -66,6 +200,41 @@ func TestServer(t *testing.T) {
 			wantHeaders: map[string]string{"Content-Type": "application/rss+xml"},
 			wantBody:    "xml",
 		},
+		{
+			name:   "valid atom feed",
+			path:   "/feeds",
+			method: http.MethodGet,
+			params: map[string]string{
+				queryParamKeywords: "golang",
+				queryParamLocation: "berlin",
+				queryParamFormat:   formatAtom,
+			},
+			wantStatus:  http.StatusOK,
+			wantHeaders: map[string]string{"Content-Type": "application/atom+xml"},
+			wantBody:    "xml",
+		},
+		{
+			name:   "unsupported feed format",
+			path:   "/feeds",
+			method: http.MethodGet,
+			params: map[string]string{
+				queryParamKeywords: "golang",
+				queryParamLocation: "berlin",
+				queryParamFormat:   "yaml",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid feed limit",
+			path:   "/feeds",
+			method: http.MethodGet,
+			params: map[string]string{
+				queryParamKeywords: "golang",
+				queryParamLocation: "berlin",
+				queryParamLimit:    "-1",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:   "invalid feed", // Returns a valid xml with a single post with instructions.
 			path:   "/feeds",
@@ -87,6 +256,35 @@ func TestServer(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name:   "delete existing feed",
+			path:   "/feeds",
+			method: http.MethodDelete,
+			params: map[string]string{
+				queryParamKeywords: "data scientist",
+				queryParamLocation: "new york",
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "delete non existing feed",
+			path:   "/feeds",
+			method: http.MethodDelete,
+			params: map[string]string{
+				queryParamKeywords: "data scientist",
+				queryParamLocation: "new york",
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:   "delete with missing param keywords",
+			path:   "/feeds",
+			method: http.MethodDelete,
+			params: map[string]string{
+				queryParamLocation: "new york",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "help page",
 			path:       "/help",
@@ -142,6 +340,9 @@ func TestServer(t *testing.T) {
 					s = regexp.MustCompile(`<link>[^<]*</link>`).ReplaceAllString(s, `<link>LINK_SCRUBBED</link>`)
 					s = regexp.MustCompile(`<pubDate>[^<]*</pubDate>`).ReplaceAllString(s, `<pubDate>DATETIME_SCRUBBED</pubDate>`)
 					s = regexp.MustCompile(`\(posted [^)]*\)`).ReplaceAllString(s, `(posted POSTED_AT_SCRUBBED)`)
+					s = regexp.MustCompile(`<link href="[^"]*"/>`).ReplaceAllString(s, `<link href=HREF_SCRUBBED/>`)
+					s = regexp.MustCompile(`<id>[^<]*</id>`).ReplaceAllString(s, `<id>ID_SCRUBBED</id>`)
+					s = regexp.MustCompile(`<updated>[^<]*</updated>`).ReplaceAllString(s, `<updated>DATETIME_SCRUBBED</updated>`)
 					return s
 				}
 				approvals.UseFolder("approvals")
@@ -152,3 +353,1725 @@ func TestServer(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateJSON(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/feeds?keywords=golang&location=berlin", nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unable to perform http request: %v", err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("wanted content type application/json, got %s", ct)
+	}
+	var resp createResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	want := "https://" + strings.TrimPrefix(server.URL, "http://") + "/feeds?keywords=golang&location=berlin"
+	if resp.FeedURL != want {
+		t.Errorf("wanted feed url %s, got %s", want, resp.FeedURL)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	const secret = "s3cr3t"
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j, WithAdminSecret(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		query      string
+		secret     string
+		wantStatus int
+	}{
+		{name: "existing query", query: "keywords=golang&location=berlin", secret: secret, wantStatus: http.StatusAccepted},
+		{name: "unknown query", query: "keywords=cobol&location=berlin", secret: secret, wantStatus: http.StatusNotFound},
+		{name: "missing params", query: "keywords=golang", secret: secret, wantStatus: http.StatusBadRequest},
+		{name: "missing secret", query: "keywords=golang&location=berlin", wantStatus: http.StatusUnauthorized},
+		{name: "wrong secret", query: "keywords=golang&location=berlin", secret: "guess", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/feeds/refresh?"+tt.query, nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			if tt.secret != "" {
+				req.Header.Set(headerAdminSecret, tt.secret)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			r.Body.Close()
+			if r.StatusCode != tt.wantStatus {
+				t.Errorf("wanted status code %d, got %d", tt.wantStatus, r.StatusCode)
+			}
+		})
+	}
+}
+
+func TestPause(t *testing.T) {
+	const secret = "s3cr3t"
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j, WithAdminSecret(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		secret     string
+		wantStatus int
+		wantPaused bool
+	}{
+		{name: "status", method: http.MethodGet, path: "/admin/pause", secret: secret, wantStatus: http.StatusOK},
+		{name: "pause", method: http.MethodPost, path: "/admin/pause", secret: secret, wantStatus: http.StatusOK, wantPaused: true},
+		{name: "status while paused", method: http.MethodGet, path: "/admin/pause", secret: secret, wantStatus: http.StatusOK, wantPaused: true},
+		{name: "pause twice", method: http.MethodPost, path: "/admin/pause", secret: secret, wantStatus: http.StatusOK, wantPaused: true},
+		{name: "resume", method: http.MethodPost, path: "/admin/resume", secret: secret, wantStatus: http.StatusOK},
+		{name: "missing secret", method: http.MethodPost, path: "/admin/pause", wantStatus: http.StatusUnauthorized},
+		{name: "wrong secret", method: http.MethodPost, path: "/admin/resume", secret: "guess", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			if tt.secret != "" {
+				req.Header.Set(headerAdminSecret, tt.secret)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != tt.wantStatus {
+				t.Fatalf("wanted status code %d, got %d", tt.wantStatus, r.StatusCode)
+			}
+			if r.StatusCode != http.StatusOK {
+				return
+			}
+			var got pauseResponse
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if got.Paused != tt.wantPaused {
+				t.Errorf("wanted paused %t, got %t", tt.wantPaused, got.Paused)
+			}
+		})
+	}
+}
+
+func TestScrapeDebug(t *testing.T) {
+	const secret = "s3cr3t"
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	info := &scrape.DebugInfo{Pages: 1, Items: 12, Cards: 10, IDs: 10, Offers: 10}
+
+	tests := []struct {
+		name       string
+		scraper    scrape.Scraper
+		query      string
+		secret     string
+		wantStatus int
+		wantError  string
+	}{
+		{name: "debug info", scraper: &debugScraper{info: info}, query: "keywords=golang&location=berlin", secret: secret, wantStatus: http.StatusOK},
+		{name: "failed scrape", scraper: &debugScraper{info: info, err: errors.New("blocked")}, query: "keywords=golang&location=berlin", secret: secret, wantStatus: http.StatusOK, wantError: "failed to scrape: blocked"},
+		{name: "unsupported scraper", scraper: scrape.MockScraper, query: "keywords=golang&location=berlin", secret: secret, wantStatus: http.StatusNotImplemented},
+		{name: "missing params", scraper: &debugScraper{info: info}, query: "keywords=golang", secret: secret, wantStatus: http.StatusBadRequest},
+		{name: "missing secret", scraper: &debugScraper{info: info}, query: "keywords=golang&location=berlin", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, jCloser := jobber.NewConfigurableJobber(l, d, tt.scraper)
+			defer jCloser()
+			svr, err := New(l, j, WithAdminSecret(secret))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(svr.Handler)
+			defer server.Close()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/admin/scrape-debug?"+tt.query, nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			if tt.secret != "" {
+				req.Header.Set(headerAdminSecret, tt.secret)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != tt.wantStatus {
+				t.Fatalf("wanted status code %d, got %d", tt.wantStatus, r.StatusCode)
+			}
+			if r.StatusCode != http.StatusOK {
+				return
+			}
+			var got scrapeDebugResponse
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				t.Fatalf("unable to decode response: %v", err)
+			}
+			if got.Debug == nil || *got.Debug != *info {
+				t.Errorf("wanted debug info %+v, got %+v", *info, got.Debug)
+			}
+			if got.Error != tt.wantError {
+				t.Errorf("wanted error %q, got %q", tt.wantError, got.Error)
+			}
+		})
+	}
+}
+
+// debugScraper returns the same debug info for every scrape.
+type debugScraper struct {
+	info *scrape.DebugInfo
+	err  error
+}
+
+func (s *debugScraper) Scrape(_ context.Context, _ *db.Query) ([]db.CreateOfferParams, error) {
+	return nil, s.err
+}
+
+func (s *debugScraper) ScrapeDebug(_ context.Context, _ *db.Query) ([]db.CreateOfferParams, *scrape.DebugInfo, error) {
+	return nil, s.info, s.err
+}
+
+func (s *debugScraper) Name() string { return "mock" }
+
+func TestFeedReadsMetric(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	reads := func(keywords, location string) float64 {
+		m := &dto.Metric{}
+		if err := metrics.FeedReads.WithLabelValues(keywords, location).Write(m); err != nil {
+			t.Fatalf("failed to write metric: %v", err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	read := func(query string) {
+		r, err := http.Get(server.URL + "/feeds?" + query)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		r.Body.Close()
+	}
+
+	before := reads("golang", "berlin")
+	read("keywords=golang&location=berlin")
+	read("keywords=golang&location=berlin&format=json")
+	if got := reads("golang", "berlin") - before; got != 2 {
+		t.Errorf("wanted 2 feed reads, got %v", got)
+	}
+
+	// Unknown queries don't add series.
+	read("keywords=cobol&location=mars")
+	if got := reads("cobol", "mars"); got != 0 {
+		t.Errorf("wanted unknown queries not to be counted, got %v reads", got)
+	}
+}
+
+func TestFeedCacheHits(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+
+	items := func(server *httptest.Server) int {
+		r, err := http.Get(server.URL + "/feeds?keywords=golang&location=berlin&format=json")
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		defer r.Body.Close()
+		var feed jsonFeed
+		if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
+			t.Fatalf("unable to decode json feed: %v", err)
+		}
+		return len(feed.Items)
+	}
+	// addOffer changes the feed behind the cache's back.
+	addOffer := func(id string) {
+		o := &db.CreateOfferParams{ID: id, Source: scrape.SourceLinkedIn, Title: "Gopher", Company: "Acme", PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+		if _, err := d.CreateOffer(context.Background(), o); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+		if err := d.CreateQueryOfferAssoc(context.Background(), &db.CreateQueryOfferAssocParams{QueryID: q.ID, OfferSource: o.Source, OfferID: o.ID}); err != nil {
+			t.Fatalf("unable to create query offer association: %v", err)
+		}
+	}
+
+	t.Run("rapid reads hit the db once", func(t *testing.T) {
+		svr, err := New(l, j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(svr.Handler)
+		defer server.Close()
+
+		want := items(server)
+		addOffer("cached_offer")
+		if got := items(server); got != want {
+			t.Errorf("wanted the cached feed with %d items, got %d", want, got)
+		}
+	})
+
+	t.Run("disabled cache", func(t *testing.T) {
+		svr, err := New(l, j, WithFeedCacheTTL(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := httptest.NewServer(svr.Handler)
+		defer server.Close()
+
+		want := items(server) + 1
+		addOffer("uncached_offer")
+		if got := items(server); got != want {
+			t.Errorf("wanted the fresh feed with %d items, got %d", want, got)
+		}
+	})
+}
+
+func TestFeedConditionalRequests(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "cached feeds"},
+		{name: "rendered feeds", opts: []Option{WithFeedCacheTTL(0)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			svr, err := New(l, j, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(svr.Handler)
+			defer server.Close()
+
+			get := func(headers map[string]string) *http.Response {
+				req, err := http.NewRequest(http.MethodGet, server.URL+"/feeds?keywords=golang&location=berlin", nil)
+				if err != nil {
+					t.Fatalf("unable to create request: %v", err)
+				}
+				for k, v := range headers {
+					req.Header.Set(k, v)
+				}
+				r, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("unable to perform http request: %v", err)
+				}
+				r.Body.Close()
+				return r
+			}
+
+			r := get(nil)
+			if r.StatusCode != http.StatusOK {
+				t.Fatalf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+			}
+			etag, lastModified := r.Header.Get("ETag"), r.Header.Get("Last-Modified")
+			if etag == "" || lastModified == "" {
+				t.Fatalf("wanted the ETag and Last-Modified headers, got %q and %q", etag, lastModified)
+			}
+
+			tests := []struct {
+				name       string
+				headers    map[string]string
+				wantStatus int
+			}{
+				{name: "matching etag", headers: map[string]string{"If-None-Match": etag}, wantStatus: http.StatusNotModified},
+				{name: "stale etag", headers: map[string]string{"If-None-Match": `"stale"`}, wantStatus: http.StatusOK},
+				{name: "not modified since", headers: map[string]string{"If-Modified-Since": lastModified}, wantStatus: http.StatusNotModified},
+				{name: "modified since", headers: map[string]string{"If-Modified-Since": "Mon, 02 Jan 2006 15:04:05 GMT"}, wantStatus: http.StatusOK},
+			}
+			for _, tt := range tests {
+				if got := get(tt.headers).StatusCode; got != tt.wantStatus {
+					t.Errorf("%s: wanted status code %d, got %d", tt.name, tt.wantStatus, got)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONFeed(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	r, err := http.Get(server.URL + "/feeds?keywords=golang&location=berlin&format=json")
+	if err != nil {
+		t.Fatalf("unable to perform http request: %v", err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/feed+json" {
+		t.Errorf("wanted content type application/feed+json, got %s", ct)
+	}
+
+	var feed jsonFeed
+	if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
+		t.Fatalf("unable to decode json feed: %v", err)
+	}
+	if feed.Version != jsonFeedVersion {
+		t.Errorf("wanted version %s, got %s", jsonFeedVersion, feed.Version)
+	}
+	offers, err := j.ListOffers(context.Background(), "golang", "berlin", 0, 0)
+	if err != nil {
+		t.Fatalf("unable to list offers: %v", err)
+	}
+	if len(feed.Items) != len(offers) {
+		t.Fatalf("wanted %d items, got %d", len(offers), len(feed.Items))
+	}
+	for i, o := range offers {
+		item := feed.Items[i]
+		if item.ID != o.ID {
+			t.Errorf("wanted item id %s, got %s", o.ID, item.ID)
+		}
+		if item.URL != offerLink(o) {
+			t.Errorf("wanted item url to point to the offer, got %s", item.URL)
+		}
+		if item.DatePublished != o.PostedAt.Time.Format(time.RFC3339) {
+			t.Errorf("wanted date_published %s, got %s", o.PostedAt.Time.Format(time.RFC3339), item.DatePublished)
+		}
+	}
+}
+
+func TestCompanyFilter(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	// The seed's golang in berlin query has an offer from Späti GmbH.
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+	for _, c := range []string{"Acme", "Recruiter Ltd"} {
+		o := &db.CreateOfferParams{ID: c, Source: scrape.SourceLinkedIn, Title: "Gopher", Company: c, PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}
+		if _, err := d.CreateOffer(context.Background(), o); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+		if err := d.CreateQueryOfferAssoc(context.Background(), &db.CreateQueryOfferAssocParams{QueryID: q.ID, OfferSource: o.Source, OfferID: o.ID}); err != nil {
+			t.Fatalf("unable to create query offer association: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		params        string
+		wantCompanies []string
+	}{
+		{name: "no filters", wantCompanies: []string{"Acme", "Recruiter Ltd", "Späti GmbH"}},
+		{name: "include only", params: "&company=acme&company=SPÄTI+GMBH", wantCompanies: []string{"Acme", "Späti GmbH"}},
+		{name: "exclude only", params: "&exclude_company=recruiter+ltd", wantCompanies: []string{"Acme", "Späti GmbH"}},
+		{name: "include and exclude", params: "&company=acme&company=recruiter+ltd&exclude_company=Recruiter+Ltd", wantCompanies: []string{"Acme"}},
+		{name: "no matches", params: "&company=nobody"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.Get(server.URL + "/feeds?keywords=golang&location=berlin&format=json" + tt.params)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			defer r.Body.Close()
+			var feed jsonFeed
+			if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
+				t.Fatalf("unable to decode json feed: %v", err)
+			}
+			var got []string
+			for _, i := range feed.Items {
+				got = append(got, i.Authors[0].Name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(tt.wantCompanies, got) {
+				t.Errorf("wanted companies %v, got %v", tt.wantCompanies, got)
+			}
+		})
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	// The seed's golang in berlin query has an offer from Späti GmbH posted now.
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+	for c, ago := range map[string]time.Duration{"Acme": 2 * time.Hour, "Globex": 3 * 24 * time.Hour} {
+		o := &db.CreateOfferParams{ID: c, Source: scrape.SourceLinkedIn, Title: "Gopher", Company: c, PostedAt: pgtype.Timestamptz{Time: time.Now().Add(-ago), Valid: true}}
+		if _, err := d.CreateOffer(context.Background(), o); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+		if err := d.CreateQueryOfferAssoc(context.Background(), &db.CreateQueryOfferAssocParams{QueryID: q.ID, OfferSource: o.Source, OfferID: o.ID}); err != nil {
+			t.Fatalf("unable to create query offer association: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		maxAge        string
+		wantStatus    int
+		wantCompanies []string
+	}{
+		{name: "without max age", wantStatus: http.StatusOK, wantCompanies: []string{"Acme", "Globex", "Späti GmbH"}},
+		{name: "in hours", maxAge: "24h", wantStatus: http.StatusOK, wantCompanies: []string{"Acme", "Späti GmbH"}},
+		{name: "in days", maxAge: "4d", wantStatus: http.StatusOK, wantCompanies: []string{"Acme", "Globex", "Späti GmbH"}},
+		{name: "invalid", maxAge: "soon", wantStatus: http.StatusBadRequest},
+		{name: "invalid days", maxAge: "1.5d", wantStatus: http.StatusBadRequest},
+		{name: "negative", maxAge: "-1h", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.Get(server.URL + "/feeds?keywords=golang&location=berlin&format=json&max_age=" + url.QueryEscape(tt.maxAge))
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != tt.wantStatus {
+				t.Fatalf("wanted status code %d, got %d", tt.wantStatus, r.StatusCode)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var feed jsonFeed
+			if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
+				t.Fatalf("unable to decode json feed: %v", err)
+			}
+			var got []string
+			for _, i := range feed.Items {
+				got = append(got, i.Authors[0].Name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(tt.wantCompanies, got) {
+				t.Errorf("wanted companies %v, got %v", tt.wantCompanies, got)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	search := func(accept string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/search?q=golang+dweeb", nil)
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("unable to read response body: %v", err)
+		}
+		return r, body
+	}
+
+	t.Run("rss by default", func(t *testing.T) {
+		r, body := search("")
+		if ct := r.Header.Get("Content-Type"); ct != "application/rss+xml" {
+			t.Errorf("wanted content type application/rss+xml, got %s", ct)
+		}
+		if !bytes.Contains(body, []byte("Junior Golang Dweeb")) {
+			t.Errorf("wanted the seed offer in the feed, got %s", body)
+		}
+	})
+
+	t.Run("json when accepted", func(t *testing.T) {
+		r, body := search("application/json")
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("wanted content type application/json, got %s", ct)
+		}
+		var resp searchResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("unable to decode response: %v", err)
+		}
+		if len(resp.Offers) != 1 || resp.Offers[0].ID != "existing_offer" {
+			t.Errorf("wanted the seed offer, got %+v", resp.Offers)
+		}
+	})
+}
+
+func TestCount(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	q, err := d.GetQuery(context.Background(), &db.GetQueryParams{Keywords: "golang", Location: "berlin"})
+	if err != nil {
+		t.Fatalf("unable to retrieve seed query: %v", err)
+	}
+	seeded, err := d.ListOffers(context.Background(), &db.ListOffersParams{ID: q.ID})
+	if err != nil {
+		t.Fatalf("unable to list seed offers: %v", err)
+	}
+	want := len(seeded)
+
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "json",
+			path:            "/api/queries/golang/berlin/count",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        fmt.Sprintf(`{"keywords":"golang","location":"berlin","count":%d}`+"\n", want),
+		},
+		{
+			name:            "plain text",
+			path:            "/api/queries/golang/berlin/count?format=text",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        fmt.Sprint(want),
+		},
+		{
+			name:            "shields.io badge",
+			path:            "/api/queries/golang/berlin/count?format=shields",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        fmt.Sprintf(`{"schemaVersion":1,"label":"golang jobs in berlin","message":"%d","color":"blue"}`+"\n", want),
+		},
+		{
+			name:            "escaped and mixed case path",
+			path:            "/api/queries/Data%20Scientist/New%20York/count?format=text",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "0",
+		},
+		{
+			name:       "unsupported format",
+			path:       "/api/queries/golang/berlin/count?format=xml",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown query",
+			path:       "/api/queries/fluffy%20dogs/the%20moon/count",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			defer r.Body.Close()
+			if r.StatusCode != tt.wantStatus {
+				t.Errorf("wanted status code %d, got %d", tt.wantStatus, r.StatusCode)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+			if ct := r.Header.Get("Content-Type"); ct != tt.wantContentType {
+				t.Errorf("wanted content type %s, got %s", tt.wantContentType, ct)
+			}
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("unable to read response body: %v", err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("wanted body %q, got %q", tt.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestFragment(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	get := func(t *testing.T, keywords, location string) string {
+		t.Helper()
+		r, err := http.Get(server.URL + "/feeds/fragment?" + url.Values{queryParamKeywords: {keywords}, queryParamLocation: {location}}.Encode())
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Errorf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("wanted content type text/html; charset=utf-8, got %s", ct)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("unable to read response body: %v", err)
+		}
+		for _, chrome := range []string{"<html", "<head", "<body", "<script"} {
+			if strings.Contains(string(body), chrome) {
+				t.Errorf("wanted a fragment without %s, got %s", chrome, body)
+			}
+		}
+		return string(body)
+	}
+
+	t.Run("lists the offers", func(t *testing.T) {
+		offers, err := j.ListOffers(context.Background(), "golang", "berlin", 0, 0)
+		if err != nil {
+			t.Fatalf("unable to list offers: %v", err)
+		}
+		body := get(t, "golang", "berlin")
+		if got := strings.Count(body, `<li class="offer">`); got != len(offers) {
+			t.Errorf("wanted %d offer rows, got %d: %s", len(offers), got, body)
+		}
+		for _, o := range offers {
+			if !strings.Contains(body, html.EscapeString(o.Title)) {
+				t.Errorf("wanted fragment to contain offer %q, got %s", o.Title, body)
+			}
+		}
+	})
+
+	t.Run("unknown query", func(t *testing.T) {
+		body := get(t, "fluffy dogs", "the moon")
+		if !strings.Contains(body, "feed not found") {
+			t.Errorf("wanted a not found row, got %s", body)
+		}
+	})
+}
+
+func TestIndexPopularFeeds(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantContain []string
+		wantMissing []string
+	}{
+		{
+			name:        "popular feeds are listed when enabled",
+			opts:        []Option{WithPopularFeeds(10)},
+			wantContain: []string{`<a href="/feeds?keywords=golang&amp;location=berlin">golang jobs in berlin</a>`},
+			// Queries unused for longer than 7 days aren't popular.
+			wantMissing: []string{"python jobs in san francisco"},
+		},
+		{
+			name:        "popular feeds aren't listed by default",
+			wantMissing: []string{"popular feed", "golang jobs in berlin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr, err := New(l, j, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(svr.Handler)
+			defer server.Close()
+
+			r, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to read response body: %v", err)
+			}
+			if r.StatusCode != http.StatusOK {
+				t.Errorf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+			}
+			for _, c := range tt.wantContain {
+				if !bytes.Contains(body, []byte(c)) {
+					t.Errorf("wanted index to contain %q, got %s", c, body)
+				}
+			}
+			for _, m := range tt.wantMissing {
+				if bytes.Contains(body, []byte(m)) {
+					t.Errorf("wanted index not to contain %q", m)
+				}
+			}
+		})
+	}
+}
+
+func TestCombinedFeed(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	// Both seed queries share existing_offer and get a new offer each.
+	ctx := context.Background()
+	for _, o := range []struct {
+		keywords, location, offerID string
+	}{
+		{"golang", "berlin", "golang_offer"},
+		{"data scientist", "new york", "data_offer"},
+		{"data scientist", "new york", "existing_offer"},
+	} {
+		q, err := d.GetQuery(ctx, &db.GetQueryParams{Keywords: o.keywords, Location: o.location})
+		if err != nil {
+			t.Fatalf("unable to get seed query: %v", err)
+		}
+		if _, err := d.CreateOffer(ctx, &db.CreateOfferParams{ID: o.offerID, PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+		if err := d.CreateQueryOfferAssoc(ctx, &db.CreateQueryOfferAssocParams{QueryID: q.ID, OfferID: o.offerID}); err != nil {
+			t.Fatalf("unable to create query offer association: %v", err)
+		}
+	}
+
+	get := func(t *testing.T, qs ...string) (*http.Response, []byte) {
+		t.Helper()
+		v := url.Values{}
+		for _, q := range qs {
+			v.Add(queryParamQuery, q)
+		}
+		r, err := http.Get(server.URL + "/feeds/combined?" + v.Encode())
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		defer r.Body.Close()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("unable to read response body: %v", err)
+		}
+		return r, body
+	}
+
+	t.Run("merges and dedups the offers", func(t *testing.T) {
+		r, body := get(t, "golang|berlin", "Data Scientist | New York", "golang|berlin")
+		if r.StatusCode != http.StatusOK {
+			t.Fatalf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+		}
+		var rss struct {
+			GUIDs []string `xml:"channel>item>guid"`
+		}
+		if err := xml.Unmarshal(body, &rss); err != nil {
+			t.Fatalf("wanted valid xml, got error: %v", err)
+		}
+		slices.Sort(rss.GUIDs)
+		want := []string{"data_offer", "existing_offer", "golang_offer"}
+		if !slices.Equal(want, rss.GUIDs) {
+			t.Errorf("wanted offers %v, got %v", want, rss.GUIDs)
+		}
+	})
+
+	t.Run("missing queries aren't created", func(t *testing.T) {
+		r, err := http.Get(server.URL + "/feeds/combined?" + url.Values{queryParamQuery: {"rust|lisbon"}, "create": {"true"}}.Encode())
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Errorf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+		}
+		if _, err := j.CountOffers(context.Background(), "rust", "lisbon"); !errors.Is(err, jobber.ErrQueryNotFound) {
+			t.Errorf("wanted the query not to be created, got %v", err)
+		}
+	})
+
+	t.Run("missing queries are created with a post", func(t *testing.T) {
+		v := url.Values{queryParamQuery: {"rust|lisbon", "golang|berlin"}}
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/feeds/combined?"+v.Encode(), nil)
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Fatalf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+		}
+		var resp createResponse
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			t.Fatalf("unable to decode response: %v", err)
+		}
+		u, err := url.Parse(resp.FeedURL)
+		if err != nil || u.Path != "/feeds/combined" || !slices.Equal(u.Query()[queryParamQuery], v[queryParamQuery]) {
+			t.Errorf("wanted the combined feed url, got %s", resp.FeedURL)
+		}
+		if _, err := j.CountOffers(context.Background(), "rust", "lisbon"); err != nil {
+			t.Errorf("wanted the missing query to be created, got %v", err)
+		}
+	})
+
+	t.Run("invalid pairs", func(t *testing.T) {
+		r, _ := get(t, "golang")
+		if r.StatusCode != http.StatusBadRequest {
+			t.Errorf("wanted status code %d, got %d", http.StatusBadRequest, r.StatusCode)
+		}
+	})
+
+	t.Run("too many queries", func(t *testing.T) {
+		var qs []string
+		for i := range maxCombinedQueries + 1 {
+			qs = append(qs, fmt.Sprintf("golang|city %d", i))
+		}
+		r, _ := get(t, qs...)
+		if r.StatusCode != http.StatusBadRequest {
+			t.Errorf("wanted status code %d, got %d", http.StatusBadRequest, r.StatusCode)
+		}
+	})
+}
+
+func TestFeedTTL(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	if _, err := j.CreateQuery(context.Background(), "rust", "porto", jobber.QueryOptions{IntervalHours: 6}); err != nil {
+		t.Fatalf("unable to create query: %v", err)
+	}
+	for query, want := range map[string]string{
+		"keywords=golang&location=berlin": "<ttl>60</ttl>",
+		"keywords=rust&location=porto":    "<ttl>360</ttl>",
+	} {
+		r, err := http.Get(server.URL + "/feeds?" + query)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			t.Fatalf("unable to read response body: %v", err)
+		}
+		if !strings.Contains(string(body), want) {
+			t.Errorf("wanted the feed of %s to contain %s, got:\n%s", query, want, body)
+		}
+	}
+}
+
+func TestRemoteFeedOfNonRemoteQuery(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	for remote, wantFound := range map[string]bool{"": true, "true": false} {
+		r, err := http.Get(server.URL + "/feeds?keywords=golang&location=berlin&remote=" + remote)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			t.Fatalf("unable to read response body: %v", err)
+		}
+		if gotFound := !strings.Contains(string(body), "feed not found"); gotFound != wantFound {
+			t.Errorf("wanted the feed with remote=%q found to be %t, got %t", remote, wantFound, gotFound)
+		}
+	}
+}
+
+func TestQueryGroupFeed(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	// Both seed queries share existing_offer and get a new offer each.
+	ctx := context.Background()
+	for _, o := range []struct {
+		keywords, location, offerID string
+	}{
+		{"golang", "berlin", "golang_offer"},
+		{"data scientist", "new york", "data_offer"},
+		{"data scientist", "new york", "existing_offer"},
+	} {
+		q, err := d.GetQuery(ctx, &db.GetQueryParams{Keywords: o.keywords, Location: o.location})
+		if err != nil {
+			t.Fatalf("unable to get seed query: %v", err)
+		}
+		if _, err := d.CreateOffer(ctx, &db.CreateOfferParams{ID: o.offerID, PostedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}); err != nil {
+			t.Fatalf("unable to create offer: %v", err)
+		}
+		if err := d.CreateQueryOfferAssoc(ctx, &db.CreateQueryOfferAssocParams{QueryID: q.ID, OfferID: o.offerID}); err != nil {
+			t.Fatalf("unable to create query offer association: %v", err)
+		}
+	}
+
+	create := func(t *testing.T, v url.Values) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/feeds?"+v.Encode(), nil)
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		r, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		return r
+	}
+
+	r := create(t, url.Values{
+		queryParamKeywords: {"golang", "Data Scientist"},
+		queryParamLocation: {"berlin", "New York"},
+	})
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+	}
+	var resp createResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	u, err := url.Parse(resp.FeedURL)
+	if err != nil || u.Path != "/feeds" || u.Query().Get(queryParamGroup) == "" {
+		t.Fatalf("wanted a group feed url, got %s", resp.FeedURL)
+	}
+
+	t.Run("merges and dedups the offers", func(t *testing.T) {
+		r, err := http.Get(server.URL + "/feeds?" + u.RawQuery)
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		defer r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Fatalf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+		}
+		var rss struct {
+			Title string   `xml:"channel>title"`
+			GUIDs []string `xml:"channel>item>guid"`
+		}
+		if err := xml.NewDecoder(r.Body).Decode(&rss); err != nil {
+			t.Fatalf("wanted valid xml, got error: %v", err)
+		}
+		slices.Sort(rss.GUIDs)
+		want := []string{"data_offer", "existing_offer", "golang_offer"}
+		if !slices.Equal(want, rss.GUIDs) {
+			t.Errorf("wanted offers %v, got %v", want, rss.GUIDs)
+		}
+		if !strings.Contains(rss.Title, "golang jobs in berlin") || !strings.Contains(rss.Title, "data scientist jobs in new york") {
+			t.Errorf("wanted the title to name both queries, got %q", rss.Title)
+		}
+	})
+
+	t.Run("pages past the listed offers", func(t *testing.T) {
+		for _, page := range []string{"offset=" + strconv.Itoa(jobber.MaxOffersLimit), "offset=450&limit=100"} {
+			r, err := http.Get(server.URL + "/feeds?" + u.RawQuery + "&" + page)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			r.Body.Close()
+			if r.StatusCode != http.StatusBadRequest {
+				t.Errorf("%s: wanted status code %d, got %d", page, http.StatusBadRequest, r.StatusCode)
+			}
+		}
+		r, err := http.Get(server.URL + "/feeds?" + u.RawQuery + "&offset=400&limit=100")
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		r.Body.Close()
+		if r.StatusCode != http.StatusOK {
+			t.Errorf("wanted status code %d for the last page, got %d", http.StatusOK, r.StatusCode)
+		}
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		r, err := http.Get(server.URL + "/feeds?group=999999")
+		if err != nil {
+			t.Fatalf("unable to perform http request: %v", err)
+		}
+		r.Body.Close()
+		if r.StatusCode != http.StatusNotFound {
+			t.Errorf("wanted status code %d, got %d", http.StatusNotFound, r.StatusCode)
+		}
+	})
+
+	t.Run("unpaired params", func(t *testing.T) {
+		r := create(t, url.Values{queryParamKeywords: {"golang", "rust"}, queryParamLocation: {"berlin"}})
+		r.Body.Close()
+		if r.StatusCode != http.StatusBadRequest {
+			t.Errorf("wanted status code %d, got %d", http.StatusBadRequest, r.StatusCode)
+		}
+	})
+}
+
+func TestCreateGroupSlowScrape(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	scpr := &blockingScraper{started: make(chan struct{}, 2), release: make(chan struct{})}
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scpr)
+	defer jCloser()
+	dbTimeout := 50 * time.Millisecond
+	svr, err := New(l, j, WithDBTimeout(dbTimeout))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	// The initial scrapes take longer than the DB timeout.
+	go func() {
+		<-scpr.started
+		<-scpr.started
+		time.Sleep(2 * dbTimeout)
+		close(scpr.release)
+	}()
+	v := url.Values{
+		queryParamKeywords: {"rust", "zig"},
+		queryParamLocation: {"lisbon", "porto"},
+	}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/feeds?"+v.Encode(), nil)
+	if err != nil {
+		t.Fatalf("unable to create request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unable to perform http request: %v", err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		t.Fatalf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+	}
+	var resp createResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		t.Fatalf("unable to decode response: %v", err)
+	}
+	u, err := url.Parse(resp.FeedURL)
+	if err != nil {
+		t.Fatalf("unable to parse feed url: %v", err)
+	}
+	id, err := strconv.ParseInt(u.Query().Get(queryParamGroup), 10, 64)
+	if err != nil {
+		t.Fatalf("wanted a group feed url, got %s", resp.FeedURL)
+	}
+	if queries, err := j.ListQueryGroup(context.Background(), id); err != nil || len(queries) != 2 {
+		t.Errorf("wanted the group stored with 2 queries, got %d (error: %v)", len(queries), err)
+	}
+}
+
+func TestParseGroupQueries(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []jobber.GroupQuery
+		wantErr bool
+	}{
+		{
+			name:  "pairs in order",
+			query: "keywords=Golang&location=Berlin&keywords=golang&location=%20remote%20",
+			want:  []jobber.GroupQuery{{Keywords: "golang", Location: "berlin"}, {Keywords: "golang", Location: "remote"}},
+		},
+		{name: "unpaired", query: "keywords=golang&keywords=rust&location=berlin", wantErr: true},
+		{name: "empty location", query: "keywords=golang&location=berlin&keywords=rust&location=%20", wantErr: true},
+		{name: "too many", query: strings.Repeat("keywords=golang&location=berlin&", maxCombinedQueries+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/feeds?"+tt.query, nil)
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseGroupQueries(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("wanted error %t, got %v", tt.wantErr, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("wanted queries %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGatheringFeed(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	d, dbCloser := db.NewTestDB(t)
+	defer dbCloser()
+	scpr := &blockingScraper{started: make(chan struct{}), release: make(chan struct{})}
+	j, jCloser := jobber.NewConfigurableJobber(l, d, scpr)
+	defer jCloser()
+	svr, err := New(l, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	created := make(chan error)
+	go func() {
+		_, err := j.CreateQuery(context.Background(), "rust", "lisbon", jobber.QueryOptions{})
+		created <- err
+	}()
+	<-scpr.started
+
+	r, err := http.Get(server.URL + "/feeds?keywords=rust&location=lisbon")
+	if err != nil {
+		t.Fatalf("unable to perform http request: %v", err)
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		t.Fatalf("unable to read response body: %v", err)
+	}
+	if r.StatusCode != http.StatusOK {
+		t.Errorf("wanted status code %d, got %d", http.StatusOK, r.StatusCode)
+	}
+	if cc := r.Header.Get("Cache-Control"); cc != "max-age=60" {
+		t.Errorf("wanted Cache-Control max-age=60 while gathering, got %q", cc)
+	}
+	if !bytes.Contains(body, []byte("we're still collecting jobs")) {
+		t.Errorf("wanted the gathering placeholder in the feed, got %s", body)
+	}
+
+	close(scpr.release)
+	if err := <-created; err != nil {
+		t.Fatalf("unable to create query: %v", err)
+	}
+	if j.Gathering("rust", "lisbon") {
+		t.Errorf("wanted gathering state to be cleared after the initial scrape")
+	}
+}
+
+// blockingScraper blocks every scrape until released.
+type blockingScraper struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingScraper) Scrape(_ context.Context, _ *db.Query) ([]db.CreateOfferParams, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return nil, nil
+}
+
+func (s *blockingScraper) Name() string { return "mock" }
+
+func TestDBTimeout(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	j, jCloser := jobber.NewConfigurableJobber(l, db.New(slowDB{delay: 200 * time.Millisecond}), scrape.MockScraper)
+	defer jCloser()
+	svr, err := New(l, j, WithDBTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(svr.Handler)
+	defer server.Close()
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			req, err := http.NewRequest(method, server.URL+"/feeds?keywords=golang&location=berlin", nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			r.Body.Close()
+			if r.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("wanted status code %d, got %d", http.StatusServiceUnavailable, r.StatusCode)
+			}
+		})
+	}
+}
+
+// slowDB is a db.DBTX whose operations take longer than the server's DB timeout.
+type slowDB struct {
+	delay time.Duration
+}
+
+func (s slowDB) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(s.delay):
+		return errors.New("slow db")
+	}
+}
+
+func (s slowDB) Exec(ctx context.Context, _ string, _ ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, s.wait(ctx)
+}
+
+func (s slowDB) Query(ctx context.Context, _ string, _ ...any) (pgx.Rows, error) {
+	return nil, s.wait(ctx)
+}
+
+func (s slowDB) QueryRow(ctx context.Context, _ string, _ ...any) pgx.Row {
+	return slowRow{err: s.wait(ctx)}
+}
+
+type slowRow struct {
+	err error
+}
+
+func (r slowRow) Scan(...any) error {
+	return r.err
+}
+
+func TestParseTemplates(t *testing.T) {
+	// validAssets returns a copy of the embedded assets.
+	validAssets := func(t *testing.T) fstest.MapFS {
+		t.Helper()
+		files, err := fs.Glob(assets, assetsGlob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := fstest.MapFS{}
+		for _, f := range files {
+			b, err := fs.ReadFile(assets, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m[f] = &fstest.MapFile{Data: b}
+		}
+		return m
+	}
+
+	t.Run("valid templates", func(t *testing.T) {
+		if _, err := parseTemplates(validAssets(t)); err != nil {
+			t.Errorf("wanted no error, got %v", err)
+		}
+	})
+
+	t.Run("syntax error names the template", func(t *testing.T) {
+		fsys := validAssets(t)
+		fsys["assets/"+assetRSS] = &fstest.MapFile{Data: []byte("<rss>{{ if .Title }}</rss>")}
+		_, err := parseTemplates(fsys)
+		if err == nil || !strings.Contains(err.Error(), "syntax error in template assets/"+assetRSS) {
+			t.Errorf("wanted a syntax error naming %s, got %v", assetRSS, err)
+		}
+	})
+
+	t.Run("missing template is named", func(t *testing.T) {
+		fsys := validAssets(t)
+		delete(fsys, "assets/"+assetHelp)
+		_, err := parseTemplates(fsys)
+		if err == nil || !strings.Contains(err.Error(), "missing template "+assetHelp) {
+			t.Errorf("wanted a missing template error naming %s, got %v", assetHelp, err)
+		}
+	})
+}
+
+func TestRSSByline(t *testing.T) {
+	tmpl, err := parseTemplates(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &feedData{
+		Keywords: "golang",
+		Location: "berlin",
+		Offers: []*db.Offer{{
+			ID:       "1",
+			Title:    "Gopher",
+			Company:  "Smith & <Sons>",
+			PostedAt: pgtype.Timestamptz{Valid: true},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, assetRSS, d); err != nil {
+		t.Fatalf("failed to execute template: %v", err)
+	}
+
+	var rss struct {
+		Items []struct {
+			Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &rss); err != nil {
+		t.Fatalf("wanted valid xml, got error: %v", err)
+	}
+	if len(rss.Items) != 1 {
+		t.Fatalf("wanted 1 item, got %d", len(rss.Items))
+	}
+	if rss.Items[0].Creator != "Smith & <Sons>" {
+		t.Errorf("wanted dc:creator to be 'Smith & <Sons>', got '%s'", rss.Items[0].Creator)
+	}
+}
+
+func TestSourceURL(t *testing.T) {
+	tmpl, err := parseTemplates(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceURL := scrape.LinkedInSearchURL(&db.Query{Keywords: "golang", Location: "berlin"})
+
+	tests := []struct {
+		name      string
+		asset     string
+		sourceURL string
+		want      bool
+	}{
+		{name: "rss includes the source url when enabled", asset: assetRSS, sourceURL: sourceURL, want: true},
+		{name: "rss omits the source url by default", asset: assetRSS},
+		{name: "atom includes the source url when enabled", asset: assetAtom, sourceURL: sourceURL, want: true},
+		{name: "atom omits the source url by default", asset: assetAtom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &feedData{Keywords: "golang", Location: "berlin", SourceURL: tt.sourceURL}
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, tt.asset, d); err != nil {
+				t.Fatalf("failed to execute template: %v", err)
+			}
+			if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+				t.Fatalf("wanted valid xml, got error: %v", err)
+			}
+			escaped := strings.ReplaceAll(sourceURL, "&", "&amp;")
+			if got := strings.Contains(buf.String(), escaped); got != tt.want {
+				t.Errorf("wanted source url in the feed to be %t, got %t: %s", tt.want, got, buf.String())
+			}
+		})
+	}
+}
+
+func TestFeedTitleEscaping(t *testing.T) {
+	tmpl, err := parseTemplates(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const raw = `go & <rust></title><item>`
+	for name, d := range map[string]*feedData{
+		"title":     {Title: raw + " jobs", ImageURL: "https://example.com/logo.png"},
+		"not found": {Keywords: raw, Location: raw, NotFound: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, assetRSS, d); err != nil {
+				t.Fatalf("failed to execute template: %v", err)
+			}
+			var rss struct {
+				Titles []string `xml:"channel>title"`
+				Items  []string `xml:"channel>item>title"`
+			}
+			if err := xml.Unmarshal(buf.Bytes(), &rss); err != nil {
+				t.Fatalf("wanted valid xml, got error: %v\n%s", err, buf.String())
+			}
+			if len(rss.Titles) != 1 || !strings.Contains(rss.Titles[0]+strings.Join(rss.Items, ""), raw) {
+				t.Errorf("wanted the raw keywords in a single title, got %q and items %q", rss.Titles, rss.Items)
+			}
+		})
+	}
+}
+
+func TestFailingFeed(t *testing.T) {
+	tmpl, err := parseTemplates(assets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		asset    string
+		failedAt time.Time
+		want     bool
+	}{
+		{name: "rss warns when the last run failed", asset: assetRSS, failedAt: time.Now(), want: true},
+		{name: "rss doesn't warn when the last run succeeded", asset: assetRSS},
+		{name: "atom warns when the last run failed", asset: assetAtom, failedAt: time.Now(), want: true},
+		{name: "atom doesn't warn when the last run succeeded", asset: assetAtom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &feedData{Keywords: "golang", Location: "berlin", FailedAt: tt.failedAt}
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, tt.asset, d); err != nil {
+				t.Fatalf("failed to execute template: %v", err)
+			}
+			if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+				t.Fatalf("wanted valid xml, got error: %v", err)
+			}
+			if got := strings.Contains(buf.String(), "having trouble collecting jobs"); got != tt.want {
+				t.Errorf("wanted the warning in the feed to be %t, got %t: %s", tt.want, got, buf.String())
+			}
+		})
+	}
+}
+
+func TestHealth(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	j, jCloser := jobber.NewConfigurableJobber(l, db.New(slowDB{delay: 200 * time.Millisecond}), scrape.MockScraper)
+	defer jCloser()
+
+	tests := []struct {
+		name       string
+		path       string
+		opts       []Option
+		wantStatus int
+	}{
+		{name: "healthz is ok while up", path: "/healthz", opts: []Option{WithPing(func(context.Context) error { return errors.New("db down") })}, wantStatus: http.StatusOK},
+		{name: "readyz is ok when the db pings", path: "/readyz", opts: []Option{WithPing(func(context.Context) error { return nil })}, wantStatus: http.StatusOK},
+		{name: "readyz is unavailable when the db doesn't ping", path: "/readyz", opts: []Option{WithPing(func(context.Context) error { return errors.New("db down") })}, wantStatus: http.StatusServiceUnavailable},
+		{name: "readyz is unavailable when the ping times out", path: "/readyz", opts: []Option{WithDBTimeout(10 * time.Millisecond), WithPing(slowDB{delay: 200 * time.Millisecond}.wait)}, wantStatus: http.StatusServiceUnavailable},
+		{name: "readyz is ok without ping", path: "/readyz", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr, err := New(l, j, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(svr.Handler)
+			defer server.Close()
+
+			r, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			r.Body.Close()
+			if r.StatusCode != tt.wantStatus {
+				t.Errorf("wanted status code %d, got %d", tt.wantStatus, r.StatusCode)
+			}
+		})
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	const key = "k3y"
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+
+	// Requests without params are rejected with a 400 after being authorized.
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		opts       []Option
+		headers    map[string]string
+		wantStatus int
+	}{
+		{name: "bearer token", method: http.MethodPost, opts: []Option{WithAPIKey(key)}, headers: map[string]string{"Authorization": "Bearer " + key}, wantStatus: http.StatusBadRequest},
+		{name: "api key header", method: http.MethodDelete, opts: []Option{WithAPIKey(key)}, headers: map[string]string{headerAPIKey: key}, wantStatus: http.StatusBadRequest},
+		{name: "wrong key", method: http.MethodPost, opts: []Option{WithAPIKey(key)}, headers: map[string]string{"Authorization": "Bearer guess"}, wantStatus: http.StatusUnauthorized},
+		{name: "missing key", method: http.MethodDelete, opts: []Option{WithAPIKey(key)}, wantStatus: http.StatusUnauthorized},
+		{name: "notifications with key", method: http.MethodPut, path: "/feeds/notifications", opts: []Option{WithAPIKey(key)}, headers: map[string]string{headerAPIKey: key}, wantStatus: http.StatusBadRequest},
+		{name: "notifications without key", method: http.MethodPut, path: "/feeds/notifications", opts: []Option{WithAPIKey(key)}, wantStatus: http.StatusUnauthorized},
+		{name: "combined feed creation without key", method: http.MethodPost, path: "/feeds/combined", opts: []Option{WithAPIKey(key)}, wantStatus: http.StatusUnauthorized},
+		{name: "combined feed creation with key", method: http.MethodPost, path: "/feeds/combined", opts: []Option{WithAPIKey(key)}, headers: map[string]string{headerAPIKey: key}, wantStatus: http.StatusBadRequest},
+		{name: "feed reads stay open", method: http.MethodGet, opts: []Option{WithAPIKey(key)}, wantStatus: http.StatusBadRequest},
+		{name: "without api key", method: http.MethodPost, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr, err := New(l, nil, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(svr.Handler)
+			defer server.Close()
+
+			path := "/feeds"
+			if tt.path != "" {
+				path = tt.path
+			}
+			req, err := http.NewRequest(tt.method, server.URL+path, nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			r, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to perform http request: %v", err)
+			}
+			r.Body.Close()
+			if r.StatusCode != tt.wantStatus {
+				t.Errorf("wanted status code %d, got %d", tt.wantStatus, r.StatusCode)
+			}
+		})
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	l := slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	tests := []struct {
+		name string
+		opts []Option
+		want Timeouts
+	}{
+		{
+			name: "defaults",
+			want: Timeouts{ReadHeader: 10 * time.Second, Read: 15 * time.Second, Write: 30 * time.Second, Idle: 120 * time.Second},
+		},
+		{
+			name: "overrides",
+			opts: []Option{WithTimeouts(Timeouts{ReadHeader: time.Second, Read: 2 * time.Second, Write: 3 * time.Second, Idle: 4 * time.Second})},
+			want: Timeouts{ReadHeader: time.Second, Read: 2 * time.Second, Write: 3 * time.Second, Idle: 4 * time.Second},
+		},
+		{
+			name: "zero values keep the defaults",
+			opts: []Option{WithTimeouts(Timeouts{Write: time.Minute})},
+			want: Timeouts{ReadHeader: 10 * time.Second, Read: 15 * time.Second, Write: time.Minute, Idle: 120 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svr, err := New(l, nil, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Timeouts{ReadHeader: svr.ReadHeaderTimeout, Read: svr.ReadTimeout, Write: svr.WriteTimeout, Idle: svr.IdleTimeout}
+			if got != tt.want {
+				t.Errorf("wanted timeouts %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}